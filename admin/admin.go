@@ -0,0 +1,206 @@
+// Package admin serves the admin endpoints of a server on a listener separate
+// from the one of its services:
+//
+//	/metrics        the metrics in the Prometheus text exposition format
+//	/debug/pprof/   the profiles of net/http/pprof
+//	/config         the effective config of the server, in YAML
+//	/buildinfo      the Go version, module and dependencies of the binary
+//	/loglevel       the level of the call logs, changed with PUT /loglevel?level=debug
+//	/health         the serving status of the server, changed with PUT /health?status=NOT_SERVING
+//
+// along with the gRPC channelz service, and the reflection service describing
+// it, e.g. for grpccli -addr localhost:9090 call grpc.channelz.v1.Channelz/GetServers.
+//
+// The endpoints only answer the requests from localhost for localhost or an IP
+// address, unless the listener requires client certificates (mutual TLS): they
+// are then open to the clients whose certificate is signed by the configured CA.
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/wangy8961/grpc-go-tutorial/metrics"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/reflection"
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithCollectors serves the metrics of the collectors on /metrics.
+func WithCollectors(collectors ...metrics.Collector) Option {
+	return func(s *Server) {
+		s.collectors = append(s.collectors, collectors...)
+	}
+}
+
+// WithConfig serves the config returned by config, marshaled in YAML, on /config.
+func WithConfig(config func() interface{}) Option {
+	return func(s *Server) {
+		s.config = config
+	}
+}
+
+// WithHealth serves the serving status reported by h on /health, e.g. to take
+// the server out of the load balancing of its clients without stopping it.
+func WithHealth(h *health.Server) Option {
+	return func(s *Server) {
+		s.health = h
+	}
+}
+
+// WithTLS serves the endpoints over TLS. If c requires and verifies the client
+// certificates, the endpoints are open to the clients other than localhost.
+func WithTLS(c *tls.Config) Option {
+	return func(s *Server) {
+		s.tls = c
+	}
+}
+
+// Server serves the admin endpoints.
+type Server struct {
+	addr       string
+	collectors []metrics.Collector
+	config     func() interface{}
+	health     *health.Server
+	tls        *tls.Config
+
+	grpcServer *grpc.Server // of channelz
+	http       *http.Server
+}
+
+// New returns a Server listening on addr, e.g. ":9090".
+func New(addr string, opts ...Option) *Server {
+	s := &Server{addr: addr}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.grpcServer = grpc.NewServer()
+	channelz.RegisterChannelzServiceToServer(s.grpcServer)
+	reflection.Register(s.grpcServer)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.Handle("/metrics", metrics.Handler(s.collectors...))
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/config", s.serveConfig)
+	mux.HandleFunc("/buildinfo", serveBuildInfo)
+	mux.HandleFunc("/loglevel", serveLogLevel)
+	mux.HandleFunc("/health", s.serveHealth)
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The channelz calls share the listener with the HTTP endpoints
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			s.grpcServer.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+	handler = s.authorize(handler)
+	if s.tls == nil {
+		// HTTP/2 without TLS, for the channelz calls
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	s.http = &http.Server{Addr: addr, Handler: handler, TLSConfig: s.tls}
+	return s
+}
+
+// Handler returns the handler of the endpoints, with the access control.
+func (s *Server) Handler() http.Handler {
+	return s.http.Handler
+}
+
+// ListenAndServe serves the endpoints until Shutdown is called, then returns
+// http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	if s.tls != nil {
+		// The certificates are in the TLS config
+		return s.http.ListenAndServeTLS("", "")
+	}
+	return s.http.ListenAndServe()
+}
+
+// Shutdown stops serving, waiting for the requests in flight until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// mutualTLS reports whether the clients must present a verified certificate.
+func (s *Server) mutualTLS() bool {
+	return s.tls != nil && s.tls.ClientAuth == tls.RequireAndVerifyClientCert
+}
+
+// authorize rejects the requests that are neither from localhost nor
+// authenticated by a client certificate. Without mutual TLS, the requests
+// must also be for a loopback name or an IP address: a page of another site
+// whose name was rebound to 127.0.0.1 (DNS rebinding) sends its own name.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.mutualTLS() && !isLoopback(r.RemoteAddr) {
+			log.Printf("admin: rejected request from %s to %s", r.RemoteAddr, r.URL.Path)
+			http.Error(w, "the admin endpoints are only served to localhost", http.StatusForbidden)
+			return
+		}
+		if !s.mutualTLS() && !isLocalHost(r.Host) {
+			log.Printf("admin: rejected request from %s to %s for host %q", r.RemoteAddr, r.URL.Path, r.Host)
+			http.Error(w, "the admin endpoints are only served for localhost or an IP address", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLocalHost reports whether host, the Host header of a request, e.g.
+// "localhost:9090", is a loopback name or an IP address.
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	return net.ParseIP(strings.Trim(host, "[]")) != nil
+}
+
+// isLoopback reports whether addr, e.g. "127.0.0.1:55704", is a loopback address.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// index lists the endpoints.
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprint(w, `/metrics        the metrics in the Prometheus text exposition format
+/debug/pprof/   the profiles of net/http/pprof
+/config         the effective config of the server
+/buildinfo      the Go version, module and dependencies of the binary
+/loglevel       the level of the call logs, changed with PUT /loglevel?level=debug
+/health         the serving status of the server, changed with PUT /health?status=NOT_SERVING
+grpc.channelz.v1.Channelz, over gRPC on the same address
+`)
+}
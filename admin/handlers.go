@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/logging"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// started is when the binary started, reported by /buildinfo.
+var started = time.Now()
+
+// serveConfig writes the effective config.
+func (s *Server) serveConfig(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.Error(w, "no config", http.StatusNotFound)
+		return
+	}
+	b, err := yaml.Marshal(s.config())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	w.Write(b)
+}
+
+// module is a module of the build info.
+type module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+}
+
+// buildInfo is written by /buildinfo.
+type buildInfo struct {
+	GoVersion string    `json:"go_version"`
+	Platform  string    `json:"platform"`
+	Path      string    `json:"path,omitempty"` // of the main package
+	Main      *module   `json:"main,omitempty"`
+	Deps      []module  `json:"deps,omitempty"`
+	Started   time.Time `json:"started"`
+	Uptime    string    `json:"uptime"`
+}
+
+// serveBuildInfo writes the build info of the binary as JSON.
+func serveBuildInfo(w http.ResponseWriter, r *http.Request) {
+	info := buildInfo{
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Started:   started,
+		Uptime:    time.Since(started).Round(time.Second).String(),
+	}
+	// Only the binaries built in module mode have the info of their modules
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Path = bi.Path
+		info.Main = &module{Path: bi.Main.Path, Version: bi.Main.Version, Sum: bi.Main.Sum}
+		for _, d := range bi.Deps {
+			if d.Replace != nil {
+				d = d.Replace
+			}
+			info.Deps = append(info.Deps, module{Path: d.Path, Version: d.Version, Sum: d.Sum})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(info)
+}
+
+// serveLogLevel writes the level of the call logs of logging.Default, and
+// changes it on PUT, to the level of the "level" parameter or of the body, e.g.
+// "debug". POST is refused: a page of another site could make the browser of an
+// operator POST a form to localhost, while a cross-site PUT needs a CORS
+// preflight, which is not answered.
+func serveLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		name := r.URL.Query().Get("level")
+		if name == "" {
+			b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name = strings.TrimSpace(string(b))
+		}
+		level, err := logging.ParseLevel(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if old := logging.Default.Level(); old != level {
+			logging.Default.SetLevel(level)
+			log.Printf("admin: log level changed from %s to %s", old, level)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintln(w, logging.Default.Level())
+}
+
+// serveHealth writes the serving status of the "service" parameter, the whole
+// server by default, and changes it on PUT, to the status of the "status"
+// parameter or of the body, e.g. "NOT_SERVING". POST is refused like by
+// serveLogLevel, and so are the services unknown to the health server, so that
+// a typo does not report a new service instead of changing an existing one.
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	if s.health == nil {
+		http.Error(w, "no health service", http.StatusNotFound)
+		return
+	}
+	service := r.URL.Query().Get("service")
+	if _, err := s.health.Check(r.Context(), &healthpb.HealthCheckRequest{Service: service}); status.Code(err) == codes.NotFound {
+		http.Error(w, fmt.Sprintf("unknown service %q", service), http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		name := r.URL.Query().Get("status")
+		if name == "" {
+			b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name = strings.TrimSpace(string(b))
+		}
+		st, ok := healthpb.HealthCheckResponse_ServingStatus_value[strings.ToUpper(name)]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown serving status %q", name), http.StatusBadRequest)
+			return
+		}
+		s.health.SetServingStatus(service, healthpb.HealthCheckResponse_ServingStatus(st))
+		log.Printf("admin: serving status of %q set to %s", service, healthpb.HealthCheckResponse_ServingStatus(st))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp, err := s.health.Check(r.Context(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, resp.GetStatus())
+}
@@ -0,0 +1,100 @@
+package bootstrap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/wangy8961/grpc-go-tutorial/discovery"
+	_ "github.com/wangy8961/grpc-go-tutorial/p2c" // registers the p2c policy
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/balancer/roundrobin" // registers the round_robin policy
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/health" // the health checks of the addresses balanced across
+	"google.golang.org/grpc/keepalive"
+)
+
+// Dial connects to the address of c with the credentials, service config,
+// load balancing, keepalive and message size settings of c, followed by opts.
+func Dial(c *ClientConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	target := c.Address
+	if addrs, ok := discovery.SplitList(c.Address); ok {
+		target = discovery.ListTarget(addrs...)
+		if c.TLS.ServerName == "" {
+			// The certificates of the servers are for the host of the first address
+			cc := *c
+			cc.TLS.ServerName, _, _ = net.SplitHostPort(addrs[0])
+			c = &cc
+		}
+	}
+	dialOpts, err := c.dialOptions()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(target, append(dialOpts, opts...)...)
+}
+
+// dialOptions returns the credentials, keepalive and message size options of c.
+func (c *ClientConfig) dialOptions() ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	if c.TLS.CAFile == "" {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		creds, err := c.TLS.credentials()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	}
+
+	sc, err := c.serviceConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.WithDefaultServiceConfig(sc))
+
+	if k := c.Keepalive; k.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                k.Time,
+			Timeout:             k.Timeout,
+			PermitWithoutStream: k.PermitWithoutStream,
+		}))
+	}
+
+	var callOpts []grpc.CallOption
+	if c.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	return opts, nil
+}
+
+// credentials returns the TLS credentials of the client, with its certificate
+// when CertFile is set.
+func (c *ClientTLSConfig) credentials() (credentials.TransportCredentials, error) {
+	if c.CertFile == "" {
+		creds, err := credentials.NewClientTLSFromFile(c.CAFile, c.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA root certificate: %v", err)
+		}
+		return creds, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	pool, err := certPool(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   c.ServerName,
+	}), nil
+}
@@ -0,0 +1,324 @@
+// Package bootstrap builds the servers and the client connections of the
+// examples from a config, instead of rebuilding the same flags, listener,
+// credentials and options in every main.go.
+//
+// The config is made of the defaults of the binary, overridden by a YAML file
+// given with -config (JSON files work as well, being YAML, and TOML files are
+// read when their extension is .toml, with the same keys), then by the
+// environment variables, then by the flags set on the command
+// line. The environment variable of a setting is its path in the file, in
+// upper case, with the EnvPrefix, e.g. APP_SERVER_ADDRESS or
+// APP_SERVER_TLS_CERT_FILE.
+// See config.example.yaml for all the settings.
+package bootstrap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/wangy8961/grpc-go-tutorial/shutdown"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the config.
+const EnvPrefix = "APP_"
+
+// Config is the config of a binary. The servers use Server, the clients Client.
+type Config struct {
+	Server  ServerConfig  `yaml:"server"`
+	Client  ClientConfig  `yaml:"client"`
+	Admin   AdminConfig   `yaml:"admin"`
+	Log     LogConfig     `yaml:"log"`
+	Tracing TracingConfig `yaml:"tracing"`
+}
+
+// ServerConfig configures a gRPC server.
+type ServerConfig struct {
+	Address        string             `yaml:"address"` // e.g. ":50051"
+	TLS            ServerTLSConfig    `yaml:"tls"`
+	Keepalive      ServerKeepalive    `yaml:"keepalive"`
+	MaxRecvMsgSize int                `yaml:"max_recv_msg_size"` // in bytes, 4 MB if 0
+	MaxSendMsgSize int                `yaml:"max_send_msg_size"` // in bytes, unlimited if 0
+	ShutdownGrace  time.Duration      `yaml:"shutdown_grace"`
+	Interceptors   InterceptorsConfig `yaml:"interceptors"`
+	Registry       RegistryConfig     `yaml:"registry"`
+}
+
+// ServerTLSConfig enables TLS when CertFile is set, and mutual TLS when
+// ClientCAFile is set too: the clients must then present a certificate
+// signed by one of its CAs.
+type ServerTLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// ServerKeepalive configures the lifecycle of the connections of a server: the
+// keepalive pings detecting the dead clients, the idle and age limits, and the
+// enforcement policy on the pings of the clients. The zero values keep the
+// defaults of gRPC, i.e. no limits and pings every 2 hours.
+type ServerKeepalive struct {
+	MaxConnectionIdle     time.Duration `yaml:"max_connection_idle"`      // close the connections without calls for this long
+	MaxConnectionAge      time.Duration `yaml:"max_connection_age"`       // close the connections after this long, e.g. to rebalance the clients
+	MaxConnectionAgeGrace time.Duration `yaml:"max_connection_age_grace"` // let the calls in flight finish for this long before closing them
+	Time                  time.Duration `yaml:"time"`                     // ping the clients after this long without activity
+	Timeout               time.Duration `yaml:"timeout"`                  // close the connection if the ping is not acknowledged in time
+	MinTime               time.Duration `yaml:"min_time"`                 // the minimum interval of the pings of the clients, closing the connection otherwise
+	PermitWithoutStream   bool          `yaml:"permit_without_stream"`    // allow the pings of the clients without calls
+}
+
+// RegistryConfig registers the services of a server in a registry, see package
+// registry, so that the clients dialing registry://<address>/<service> find it.
+// The server sends heartbeats within the TTL and deregisters when it shuts
+// down gracefully.
+type RegistryConfig struct {
+	Address   string            `yaml:"address"`   // e.g. "localhost:50050", not registered if empty
+	Advertise string            `yaml:"advertise"` // the address of the server for the clients, by default the host of the server address, or localhost, with the port listened on
+	TTL       time.Duration     `yaml:"ttl"`       // how long the server stays registered without heartbeats, the default of the registry if 0
+	Weight    int               `yaml:"weight"`    // the relative share of the calls, 1 if 0
+	Metadata  map[string]string `yaml:"metadata"`  // e.g. zone: eu-west-1a, not set by the environment variables
+}
+
+// InterceptorsConfig enables the common interceptors of a server. They run in
+// this order, before the interceptors of the binary.
+type InterceptorsConfig struct {
+	Tracing  bool `yaml:"tracing"`
+	Logging  bool `yaml:"logging"`
+	Metrics  bool `yaml:"metrics"`
+	Recovery bool `yaml:"recovery"`
+}
+
+// ClientConfig configures a client connection. The address is either one
+// address, a comma-separated list of addresses or a target resolved to several
+// addresses, e.g. "dns:///math.example.com:50051", "file:///etc/endpoints.yaml"
+// or "registry://localhost:50050/math.Math", see package discovery: the calls
+// are then balanced across them by the LoadBalancing policy.
+//
+// The calls follow the service config of gRPC, see ServiceConfigFile: a call
+// without a deadline gets the timeout of its method, and the shorter of the
+// two applies otherwise. The message size limits of the service config and of
+// MaxRecvMsgSize and MaxSendMsgSize apply too, the smaller one winning.
+type ClientConfig struct {
+	Address           string          `yaml:"address"`             // e.g. "localhost:50051" or "localhost:50051,localhost:50052"
+	LoadBalancing     string          `yaml:"load_balancing"`      // round_robin, p2c to favor the least loaded addresses, or pick_first to send all the calls to the first address that works
+	HealthCheck       bool            `yaml:"health_check"`        // skip the addresses whose health service does not report SERVING
+	ServiceConfigFile string          `yaml:"service_config_file"` // the per-method timeouts, wait-for-ready, retries and message sizes, none if empty
+	TLS               ClientTLSConfig `yaml:"tls"`
+	Keepalive         ClientKeepalive `yaml:"keepalive"`
+	MaxRecvMsgSize    int             `yaml:"max_recv_msg_size"`
+	MaxSendMsgSize    int             `yaml:"max_send_msg_size"`
+}
+
+// ClientTLSConfig enables TLS when CAFile is set, and mutual TLS when CertFile
+// is set too.
+type ClientTLSConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	ServerName string `yaml:"server_name"` // by default the host of the address
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+}
+
+// ClientKeepalive configures the keepalive pings of a client, which detect the
+// dead servers and keep the idle connections open through the middleboxes.
+// Time must not be shorter than the min_time of the servers, which close the
+// connections of the clients pinging too often; gRPC raises it to 10s at least.
+// The clients have no idle timeout, the servers close the idle connections
+// with max_connection_idle and the clients reconnect on their next call.
+type ClientKeepalive struct {
+	Time                time.Duration `yaml:"time"`                  // ping the server after this long without activity, no pings if 0
+	Timeout             time.Duration `yaml:"timeout"`               // close the connection if the ping is not acknowledged in time
+	PermitWithoutStream bool          `yaml:"permit_without_stream"` // ping without calls too
+}
+
+// AdminConfig configures the admin endpoints, see package admin. They only
+// answer the requests from localhost, unless TLS.ClientCAFile is set: they are
+// then open to the clients presenting a certificate signed by this CA.
+type AdminConfig struct {
+	Address string          `yaml:"address"` // e.g. ":9090", disabled if empty
+	TLS     ServerTLSConfig `yaml:"tls"`
+}
+
+// LogConfig configures the logging interceptors.
+type LogConfig struct {
+	Level    string  `yaml:"level"`    // debug, info, warn or error
+	Sampling float64 `yaml:"sampling"` // the fraction of the successful calls logged
+}
+
+// TracingConfig configures the tracing interceptors.
+type TracingConfig struct {
+	File string `yaml:"file"` // the spans are appended to it as JSON lines, not exported if empty
+}
+
+// DefaultConfig returns the config shared by the binaries, which change it to
+// their own defaults before registering the flags.
+func DefaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address: ":50051",
+			// Detect the dead clients within a minute and a half, and accept
+			// the pings of the clients below
+			Keepalive: ServerKeepalive{
+				Time:                time.Minute,
+				Timeout:             20 * time.Second,
+				MinTime:             10 * time.Second,
+				PermitWithoutStream: true,
+			},
+			ShutdownGrace: shutdown.DefaultGrace,
+		},
+		Client: ClientConfig{
+			Address:       "localhost:50051",
+			LoadBalancing: "round_robin",
+			HealthCheck:   true,
+			// Keep the idle streams, e.g. of Maximum, open through the
+			// middleboxes dropping the connections without traffic
+			Keepalive: ClientKeepalive{
+				Time:                time.Minute,
+				Timeout:             20 * time.Second,
+				PermitWithoutStream: true,
+			},
+		},
+		Log: LogConfig{Level: "info", Sampling: 1},
+	}
+}
+
+// clone returns a deep copy of c: the maps of the copy may be changed, e.g.
+// by loadFile, without changing those of c.
+func (c *Config) clone() Config {
+	cp := *c
+	if c.Server.Registry.Metadata != nil {
+		cp.Server.Registry.Metadata = make(map[string]string, len(c.Server.Registry.Metadata))
+		for k, v := range c.Server.Registry.Metadata {
+			cp.Server.Registry.Metadata[k] = v
+		}
+	}
+	return cp
+}
+
+// loadFile overrides c with the settings of the YAML file at path, or of the
+// TOML file if its extension is .toml.
+func (c *Config) loadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	isTOML := strings.EqualFold(filepath.Ext(path), ".toml")
+	if isTOML {
+		if b, err = tomlToYAML(b); err != nil {
+			return fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+	// The strict decoding refuses the keys of the file already in a map, the
+	// metadata of the file is merged into the one of c afterwards instead
+	metadata := c.Server.Registry.Metadata
+	c.Server.Registry.Metadata = nil
+	defer func() {
+		if metadata == nil {
+			return
+		}
+		for k, v := range c.Server.Registry.Metadata {
+			metadata[k] = v
+		}
+		c.Server.Registry.Metadata = metadata
+	}()
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		if te, ok := err.(*yaml.TypeError); ok && isTOML {
+			// The line numbers are those of the converted document, not of the file
+			for i, e := range te.Errors {
+				if strings.HasPrefix(e, "line ") {
+					te.Errors[i] = e[strings.Index(e, ": ")+2:]
+				}
+			}
+		}
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// tomlToYAML converts a TOML document to YAML, so that TOML files are decoded
+// with the yaml tags and the strict checks of the YAML ones.
+func tomlToYAML(b []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// loadEnv overrides c with the environment variables.
+func (c *Config) loadEnv() error {
+	return walk(reflect.ValueOf(c).Elem(), EnvPrefix, func(v reflect.Value, name string) error {
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := set(v, s); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+// walk calls fn with the leaf fields of the struct v and the names of their
+// environment variables, prefix followed by their path.
+func walk(v reflect.Value, prefix string, fn func(v reflect.Value, name string) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		name := prefix + strings.ToUpper(tag)
+		f := v.Field(i)
+		var err error
+		if f.Kind() == reflect.Struct {
+			err = walk(f, name+"_", fn)
+		} else {
+			err = fn(f, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set sets the field v to the value s.
+func set(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package bootstrap
+
+import (
+	"flag"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Flags are the -config flag and the flags overriding the config.
+type Flags struct {
+	fs       *flag.FlagSet
+	defaults *Config
+	path     *string
+	apply    map[string]func(c *Config) // applies the value of a flag set on the command line
+}
+
+func newFlags(fs *flag.FlagSet, defaults *Config) *Flags {
+	return &Flags{
+		fs:       fs,
+		defaults: defaults,
+		path:     fs.String("config", "", "the YAML, JSON or TOML (.toml) config file, overridden by the APP_* environment variables and the flags"),
+		apply:    make(map[string]func(c *Config)),
+	}
+}
+
+func (f *Flags) string(name, value, usage string, set func(c *Config, v string)) {
+	p := f.fs.String(name, value, usage)
+	f.apply[name] = func(c *Config) { set(c, *p) }
+}
+
+func (f *Flags) int(name string, value int, usage string, set func(c *Config, v int)) {
+	p := f.fs.Int(name, value, usage)
+	f.apply[name] = func(c *Config) { set(c, *p) }
+}
+
+func (f *Flags) float64(name string, value float64, usage string, set func(c *Config, v float64)) {
+	p := f.fs.Float64(name, value, usage)
+	f.apply[name] = func(c *Config) { set(c, *p) }
+}
+
+func (f *Flags) bool(name string, value bool, usage string, set func(c *Config, v bool)) {
+	p := f.fs.Bool(name, value, usage)
+	f.apply[name] = func(c *Config) { set(c, *p) }
+}
+
+func (f *Flags) duration(name string, value time.Duration, usage string, set func(c *Config, v time.Duration)) {
+	p := f.fs.Duration(name, value, usage)
+	f.apply[name] = func(c *Config) { set(c, *p) }
+}
+
+// port returns the port of addr, or 0.
+func port(addr string) int {
+	_, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(p)
+	return n
+}
+
+// withPort returns addr with port p, keeping its host.
+func withPort(addr string, p int) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p))
+}
+
+// ServerFlags registers in fs the flags of a server, with the settings of
+// defaults as default values.
+func ServerFlags(fs *flag.FlagSet, defaults *Config) *Flags {
+	f := newFlags(fs, defaults)
+	d := defaults
+	f.int("port", port(d.Server.Address), "the port to serve on", func(c *Config, v int) {
+		c.Server.Address = withPort(c.Server.Address, v)
+	})
+	f.string("certfile", d.Server.TLS.CertFile, "Server certificate, enables TLS", func(c *Config, v string) {
+		c.Server.TLS.CertFile = v
+	})
+	f.string("keyfile", d.Server.TLS.KeyFile, "Server private key", func(c *Config, v string) {
+		c.Server.TLS.KeyFile = v
+	})
+	f.string("client-cacert", d.Server.TLS.ClientCAFile, "CA root certificate of the clients, enables mutual TLS", func(c *Config, v string) {
+		c.Server.TLS.ClientCAFile = v
+	})
+	f.int("admin-port", port(d.Admin.Address), "the admin port serving /metrics, pprof, channelz, the config, the log level and the health to localhost, disabled if 0", func(c *Config, v int) {
+		if v == 0 {
+			c.Admin.Address = ""
+			return
+		}
+		c.Admin.Address = withPort(c.Admin.Address, v)
+	})
+	f.string("log-level", d.Log.Level, "the minimum level of the call logs: debug, info, warn or error", func(c *Config, v string) {
+		c.Log.Level = v
+	})
+	f.float64("log-sampling", d.Log.Sampling, "the fraction of the successful calls logged", func(c *Config, v float64) {
+		c.Log.Sampling = v
+	})
+	f.string("trace-file", d.Tracing.File, "the file the spans are appended to as JSON lines, by default they are not exported", func(c *Config, v string) {
+		c.Tracing.File = v
+	})
+	f.duration("keepalive-time", d.Server.Keepalive.Time, "ping the clients after this long without activity", func(c *Config, v time.Duration) {
+		c.Server.Keepalive.Time = v
+	})
+	f.duration("keepalive-timeout", d.Server.Keepalive.Timeout, "close the connections whose pings are not acknowledged in time", func(c *Config, v time.Duration) {
+		c.Server.Keepalive.Timeout = v
+	})
+	f.duration("max-connection-idle", d.Server.Keepalive.MaxConnectionIdle, "close the connections without calls for this long, never if 0", func(c *Config, v time.Duration) {
+		c.Server.Keepalive.MaxConnectionIdle = v
+	})
+	f.duration("max-connection-age", d.Server.Keepalive.MaxConnectionAge, "close the connections after this long, never if 0", func(c *Config, v time.Duration) {
+		c.Server.Keepalive.MaxConnectionAge = v
+	})
+	f.duration("max-connection-age-grace", d.Server.Keepalive.MaxConnectionAgeGrace, "how long the calls in flight may take to finish once a connection is too old, forever if 0", func(c *Config, v time.Duration) {
+		c.Server.Keepalive.MaxConnectionAgeGrace = v
+	})
+	f.duration("shutdown-grace", d.Server.ShutdownGrace, "how long the calls in flight may take to finish on SIGINT or SIGTERM", func(c *Config, v time.Duration) {
+		c.Server.ShutdownGrace = v
+	})
+	f.string("registry", d.Server.Registry.Address, "the registry to register the services in, e.g. localhost:50050, not registered if empty", func(c *Config, v string) {
+		c.Server.Registry.Address = v
+	})
+	f.string("advertise", d.Server.Registry.Advertise, "the address of the server registered for the clients, by default the host of the server address, or localhost, with the port", func(c *Config, v string) {
+		c.Server.Registry.Advertise = v
+	})
+	f.duration("registry-ttl", d.Server.Registry.TTL, "how long the server stays registered without heartbeats, the default of the registry if 0", func(c *Config, v time.Duration) {
+		c.Server.Registry.TTL = v
+	})
+	return f
+}
+
+// ClientFlags registers in fs the flags of a client, with the settings of
+// defaults as default values.
+func ClientFlags(fs *flag.FlagSet, defaults *Config) *Flags {
+	f := newFlags(fs, defaults)
+	d := defaults
+	f.string("addr", d.Client.Address, "the address to connect to, or a comma-separated list of addresses or a dns:///, file:/// or registry:// target to balance the calls across", func(c *Config, v string) {
+		c.Client.Address = v
+	})
+	f.string("lb", d.Client.LoadBalancing, "the load balancing policy: round_robin, p2c or pick_first", func(c *Config, v string) {
+		c.Client.LoadBalancing = v
+	})
+	f.bool("health-check", d.Client.HealthCheck, "skip the addresses whose health service does not report SERVING", func(c *Config, v bool) {
+		c.Client.HealthCheck = v
+	})
+	f.string("service-config", d.Client.ServiceConfigFile, "the JSON file of the service config with the per-method timeouts, wait-for-ready, retries and message sizes", func(c *Config, v string) {
+		c.Client.ServiceConfigFile = v
+	})
+	f.string("cacert", d.Client.TLS.CAFile, "CA root certificate, enables TLS", func(c *Config, v string) {
+		c.Client.TLS.CAFile = v
+	})
+	f.string("servername", d.Client.TLS.ServerName, "the server name of the TLS certificate, by default the host of -addr", func(c *Config, v string) {
+		c.Client.TLS.ServerName = v
+	})
+	f.string("certfile", d.Client.TLS.CertFile, "Client certificate, enables mutual TLS", func(c *Config, v string) {
+		c.Client.TLS.CertFile = v
+	})
+	f.string("keyfile", d.Client.TLS.KeyFile, "Client private key", func(c *Config, v string) {
+		c.Client.TLS.KeyFile = v
+	})
+	f.duration("keepalive-time", d.Client.Keepalive.Time, "ping the server after this long without activity, never if 0", func(c *Config, v time.Duration) {
+		c.Client.Keepalive.Time = v
+	})
+	f.duration("keepalive-timeout", d.Client.Keepalive.Timeout, "close the connection if the pings are not acknowledged in time", func(c *Config, v time.Duration) {
+		c.Client.Keepalive.Timeout = v
+	})
+	return f
+}
+
+// Load returns the config: the defaults, overridden by the file of -config,
+// then by the environment variables, then by the flags set on the command
+// line. It must be called after the flags are parsed.
+func (f *Flags) Load() (*Config, error) {
+	c := f.defaults.clone()
+	if *f.path != "" {
+		if err := c.loadFile(*f.path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.loadEnv(); err != nil {
+		return nil, err
+	}
+	f.fs.Visit(func(fl *flag.Flag) {
+		if apply, ok := f.apply[fl.Name]; ok {
+			apply(&c)
+		}
+	})
+	return &c, nil
+}
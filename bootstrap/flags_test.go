@@ -0,0 +1,57 @@
+package bootstrap
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// load parses args with the server flags of defaults, the config file having
+// the YAML content config, and returns the loaded config.
+func load(t *testing.T, defaults *Config, config string, args ...string) *Config {
+	f, err := ioutil.TempFile("", "config_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(config)
+	f.Close()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	flags := ServerFlags(fs, defaults)
+	if err := fs.Parse(append([]string{"-config", f.Name()}, args...)); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	c, err := flags.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	return c
+}
+
+func TestAdminPortKeepsHost(t *testing.T) {
+	c := load(t, DefaultConfig(), "admin:\n  address: 127.0.0.1:9090\n", "-admin-port", "9191")
+	if got, want := c.Admin.Address, "127.0.0.1:9191"; got != want {
+		t.Errorf("Admin.Address = %q, want %q", got, want)
+	}
+
+	c = load(t, DefaultConfig(), "admin:\n  address: 127.0.0.1:9090\n", "-admin-port", "0")
+	if c.Admin.Address != "" {
+		t.Errorf("Admin.Address = %q with -admin-port 0, want it disabled", c.Admin.Address)
+	}
+}
+
+func TestLoadKeepsDefaults(t *testing.T) {
+	defaults := DefaultConfig()
+	defaults.Server.Registry.Metadata = map[string]string{"zone": "eu-west-1a", "env": "prod"}
+
+	// The metadata of the file is merged into the one of the defaults
+	c := load(t, defaults, "server:\n  registry:\n    metadata:\n      zone: us-east-1a\n      rack: r1\n")
+	if got := c.Server.Registry.Metadata; len(got) != 3 || got["zone"] != "us-east-1a" || got["rack"] != "r1" || got["env"] != "prod" {
+		t.Errorf("Metadata = %v, want the zone and rack of the file and the env of the defaults", got)
+	}
+	if got := defaults.Server.Registry.Metadata; len(got) != 2 || got["zone"] != "eu-west-1a" {
+		t.Errorf("Metadata of the defaults = %v after Load, want them unchanged", got)
+	}
+}
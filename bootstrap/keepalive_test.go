@@ -0,0 +1,216 @@
+package bootstrap_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"google.golang.org/grpc"
+)
+
+// proxy forwards the TCP connections to backend, until blackhole is called:
+// it then drops the bytes in both directions but keeps the connections open,
+// like a middlebox dropping a connection without resetting it.
+type proxy struct {
+	lis     net.Listener
+	backend string
+	dropped int32 // 1 once blackhole is called
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newProxy(backend string) (*proxy, error) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &proxy{lis: lis, backend: backend}
+	go p.serve()
+	return p, nil
+}
+
+func (p *proxy) serve() {
+	for {
+		client, err := p.lis.Accept()
+		if err != nil {
+			return
+		}
+		server, err := net.Dial("tcp", p.backend)
+		if err != nil {
+			client.Close()
+			continue
+		}
+		p.mu.Lock()
+		p.conns = append(p.conns, client, server)
+		p.mu.Unlock()
+		go p.pipe(server, client)
+		go p.pipe(client, server)
+	}
+}
+
+// pipe copies src to dst, or drops what it reads once blackholed. A
+// blackholed connection is not closed either when the other side closes it.
+func (p *proxy) pipe(dst, src net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if err != nil {
+			if atomic.LoadInt32(&p.dropped) == 0 {
+				dst.Close()
+			}
+			return
+		}
+		if atomic.LoadInt32(&p.dropped) == 1 {
+			continue
+		}
+		if _, err := dst.Write(buf[:n]); err != nil {
+			src.Close()
+			return
+		}
+	}
+}
+
+func (p *proxy) blackhole() {
+	atomic.StoreInt32(&p.dropped, 1)
+}
+
+func (p *proxy) Close() {
+	p.lis.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		c.Close()
+	}
+}
+
+// deadPeer is when each side noticed that the stream was dead, after the proxy
+// stopped forwarding, 0 if it did not.
+type deadPeer struct {
+	server, client time.Duration
+}
+
+// runDeadPeer opens a bidirectional stream through a proxy, blackholes the
+// proxy once the stream is idle, then waits at most wait for both sides to give up.
+func runDeadPeer(t *testing.T, cfg *bootstrap.Config, wait time.Duration) deadPeer {
+	var r deadPeer
+	var blackholed atomic.Value // time.Time
+	serverDone := make(chan time.Duration, 1)
+	s, err := bootstrap.NewServer(cfg,
+		// Time the end of the stream on the server side
+		bootstrap.WithStreamInterceptors(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := handler(srv, ss)
+			if start, ok := blackholed.Load().(time.Time); ok {
+				serverDone <- time.Since(start)
+			}
+			return err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pb.RegisterEchoServer(s.Server, &echoserver.Server{})
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go s.Server.Serve(lis)
+	defer s.Server.Stop()
+
+	p, err := newProxy(lis.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	defer p.Close()
+
+	client := cfg.Client
+	client.Address = p.lis.Addr().String()
+	conn, err := bootstrap.Dial(&client)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// The stream is idle after the first message
+	stream, err := pb.NewEchoClient(conn).BidirectionalStreamingEcho(context.Background())
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	if err := stream.Send(&pb.EchoRequest{Message: "madmalls.com"}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	blackholed.Store(time.Now())
+	p.blackhole()
+	clientDone := make(chan time.Duration, 1)
+	go func() {
+		// The stream may only end once the test is over, without keepalive
+		stream.Recv()
+		clientDone <- time.Since(blackholed.Load().(time.Time))
+	}()
+
+	timeout := time.After(wait)
+	for r.server == 0 || r.client == 0 {
+		select {
+		case r.server = <-serverDone:
+			t.Logf("server: stream ended after %v", r.server.Round(10*time.Millisecond))
+		case r.client = <-clientDone:
+			t.Logf("client: stream ended after %v", r.client.Round(10*time.Millisecond))
+		case <-timeout:
+			return r
+		}
+	}
+	return r
+}
+
+// Without activity for Time, a ping is sent on the next tick, and the
+// connection closed if it is not acknowledged within Timeout. gRPC raises the
+// Time of the servers to at least 1s, the one of the clients to at least 10s.
+const (
+	serverTime     = time.Second
+	clientTime     = 10 * time.Second
+	pingTimeout    = 500 * time.Millisecond
+	serverWindow   = 2*serverTime + pingTimeout
+	clientWindow   = 2*clientTime + pingTimeout
+	keepaliveSlack = time.Second
+)
+
+func TestKeepaliveDetectsDeadPeers(t *testing.T) {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Keepalive = bootstrap.ServerKeepalive{Time: serverTime, Timeout: pingTimeout, MinTime: clientTime, PermitWithoutStream: true}
+	cfg.Client.Keepalive = bootstrap.ClientKeepalive{Time: clientTime, Timeout: pingTimeout, PermitWithoutStream: true}
+
+	// The client window takes more than 20s, only the server is checked with -short
+	wait := clientWindow + keepaliveSlack
+	if testing.Short() {
+		wait = serverWindow + keepaliveSlack
+	}
+	r := runDeadPeer(t, cfg, wait)
+	if r.server == 0 || r.server > serverWindow+keepaliveSlack {
+		t.Errorf("the server did not detect the dead client within %v", serverWindow)
+	}
+	if !testing.Short() && (r.client == 0 || r.client > clientWindow+keepaliveSlack) {
+		t.Errorf("the client did not detect the dead server within %v", clientWindow)
+	}
+}
+
+func TestWithoutKeepaliveDeadPeersHang(t *testing.T) {
+	// The defaults of gRPC: the server pings after 2 hours, the client never
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Keepalive = bootstrap.ServerKeepalive{}
+	cfg.Client.Keepalive = bootstrap.ClientKeepalive{}
+
+	r := runDeadPeer(t, cfg, serverWindow+keepaliveSlack)
+	if r.server != 0 || r.client != 0 {
+		t.Errorf("the dead peers were detected without keepalive, the proxy did not blackhole the connection")
+	}
+}
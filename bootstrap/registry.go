@@ -0,0 +1,90 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/registry"
+	pb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc"
+)
+
+// deregisterTimeout bounds the deregistration of a server shutting down.
+const deregisterTimeout = time.Second
+
+// register registers the services of the server listening on addr in the
+// registry of the config, if any, and returns the function deregistering
+// them, which does nothing if there is no registry. Close deregisters them too.
+func (s *Server) register(addr net.Addr) (func(), error) {
+	c := s.Config.Server.Registry
+	if c.Address == "" {
+		return func() {}, nil
+	}
+	advertise, err := c.advertise(s.Config.Server.Address, addr)
+	if err != nil {
+		return nil, err
+	}
+	// The registry is a development tool, served without TLS
+	conn, err := grpc.Dial(c.Address, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial registry: %v", err)
+	}
+
+	// The services registered so far, before the health and reflection ones
+	var services []string
+	for name := range s.Server.GetServiceInfo() {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	r := registry.Register(conn, &pb.Instance{
+		Address:  advertise,
+		Services: services,
+		Weight:   int32(c.Weight),
+		Metadata: c.Metadata,
+	}, c.TTL)
+	fmt.Printf("registering %s for %v in the registry at %s\n", advertise, services, c.Address)
+
+	var once sync.Once
+	deregister := func() {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+			defer cancel()
+			if err := r.Deregister(ctx); err != nil {
+				log.Printf("failed to deregister %s: %v", advertise, err)
+			} else {
+				log.Printf("deregistered %s", advertise)
+			}
+			conn.Close()
+		})
+	}
+	s.closers = append(s.closers, func() error {
+		deregister()
+		return nil
+	})
+	return deregister, nil
+}
+
+// advertise returns the address registered for a server configured with the
+// address addr, e.g. ":50051", and listening on lis.
+func (c *RegistryConfig) advertise(addr string, lis net.Addr) (string, error) {
+	if c.Advertise != "" {
+		return c.Advertise, nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address: %v", err)
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	_, port, err := net.SplitHostPort(lis.String())
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,295 @@
+package bootstrap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/wangy8961/grpc-go-tutorial/admin"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	"github.com/wangy8961/grpc-go-tutorial/logging"
+	"github.com/wangy8961/grpc-go-tutorial/metrics"
+	"github.com/wangy8961/grpc-go-tutorial/recovery"
+	"github.com/wangy8961/grpc-go-tutorial/shutdown"
+	"github.com/wangy8961/grpc-go-tutorial/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// Option configures a server built by NewServer.
+type Option func(*options)
+
+type options struct {
+	unary      []grpc.UnaryServerInterceptor
+	stream     []grpc.StreamServerInterceptor
+	recovery   []recovery.Option
+	collectors []metrics.Collector
+	serverOpts []grpc.ServerOption
+}
+
+// WithUnaryInterceptors adds unary interceptors of the binary, which run after the common ones.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) { o.unary = append(o.unary, interceptors...) }
+}
+
+// WithStreamInterceptors adds streaming interceptors of the binary, which run after the common ones.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) { o.stream = append(o.stream, interceptors...) }
+}
+
+// WithRecovery adds options to the recovery interceptors, e.g. a hook.
+func WithRecovery(opts ...recovery.Option) Option {
+	return func(o *options) { o.recovery = append(o.recovery, opts...) }
+}
+
+// WithCollectors adds metrics served on /metrics of the admin address along with the ones of the server.
+func WithCollectors(collectors ...metrics.Collector) Option {
+	return func(o *options) { o.collectors = append(o.collectors, collectors...) }
+}
+
+// WithServerOptions adds options to the gRPC server.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) { o.serverOpts = append(o.serverOpts, opts...) }
+}
+
+// Server is a gRPC server built from a config. The services are registered in
+// the embedded grpc.Server, then Serve serves them.
+type Server struct {
+	*grpc.Server
+	Config  *Config
+	Metrics *metrics.Metrics // nil unless the metrics interceptors are enabled
+	Tracer  *tracing.Tracer  // also for the client connections of the server, to continue its traces
+
+	collectors []metrics.Collector
+	closers    []func() error
+}
+
+// NewServer builds a server from c: it sets the level of the logs, opens the
+// trace file, and creates the gRPC server with the credentials, keepalive and
+// message size settings of c and the enabled interceptors.
+func NewServer(c *Config, opts ...Option) (*Server, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	s := &Server{Config: c, Tracer: tracing.NewTracer(nil)}
+
+	level, err := logging.ParseLevel(c.Log.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %v", err)
+	}
+	logging.Default.SetLevel(level)
+	logging.Default.SetSampling(c.Log.Sampling)
+
+	if c.Tracing.File != "" {
+		exporter, f, err := tracing.NewFileExporter(c.Tracing.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		s.closers = append(s.closers, f.Close)
+		s.Tracer = tracing.NewTracer(exporter)
+	}
+
+	serverOpts, err := c.Server.serverOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	ic := c.Server.Interceptors
+	if ic.Tracing {
+		unary = append(unary, s.Tracer.UnaryServerInterceptor())
+		stream = append(stream, s.Tracer.StreamServerInterceptor())
+	}
+	if ic.Logging {
+		unary = append(unary, logging.UnaryServerInterceptor())
+		stream = append(stream, logging.StreamServerInterceptor())
+	}
+	if ic.Metrics {
+		s.Metrics = metrics.NewServer()
+		s.collectors = append(s.collectors, s.Metrics)
+		unary = append(unary, s.Metrics.UnaryServerInterceptor())
+		stream = append(stream, s.Metrics.StreamServerInterceptor())
+	}
+	if ic.Recovery {
+		// Chained after logging and metrics, which then see the Internal errors of the panics
+		recoveryOpts := o.recovery
+		if s.Metrics != nil {
+			recoveryOpts = append([]recovery.Option{recovery.WithMetrics(s.Metrics)}, recoveryOpts...)
+		}
+		unary = append(unary, recovery.UnaryServerInterceptor(recoveryOpts...))
+		stream = append(stream, recovery.StreamServerInterceptor(recoveryOpts...))
+	}
+	unary = append(unary, o.unary...)
+	stream = append(stream, o.stream...)
+	serverOpts = append(serverOpts,
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unary...)),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(stream...)),
+	)
+	serverOpts = append(serverOpts, o.serverOpts...)
+
+	s.collectors = append(s.collectors, o.collectors...)
+	s.Server = grpc.NewServer(serverOpts...)
+	return s, nil
+}
+
+// serverOptions returns the credentials, keepalive and message size options of c.
+func (c *ServerConfig) serverOptions() ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if c.TLS.CertFile != "" {
+		creds, err := c.TLS.credentials()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	k := c.Keepalive
+	opts = append(opts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     k.MaxConnectionIdle,
+			MaxConnectionAge:      k.MaxConnectionAge,
+			MaxConnectionAgeGrace: k.MaxConnectionAgeGrace,
+			Time:                  k.Time,
+			Timeout:               k.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             k.MinTime,
+			PermitWithoutStream: k.PermitWithoutStream,
+		}),
+	)
+
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	return opts, nil
+}
+
+// credentials returns the TLS credentials of the server, requiring client
+// certificates when ClientCAFile is set.
+func (c *ServerTLSConfig) credentials() (credentials.TransportCredentials, error) {
+	tc, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(tc), nil
+}
+
+// tlsConfig returns the TLS config of the server, requiring client
+// certificates when ClientCAFile is set.
+func (c *ServerTLSConfig) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificates: %v", err)
+	}
+	tc := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if c.ClientCAFile != "" {
+		pool, err := certPool(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+// certPool returns a pool with the CA certificates of the PEM file at path.
+func certPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA root certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no CA root certificate in %s", path)
+	}
+	return pool, nil
+}
+
+// ServeAdmin serves the admin endpoints on the admin address in the
+// background, if any: the metrics, pprof, channelz, the effective config, the
+// build info, the log level and the serving status reported by h, unless nil.
+func (s *Server) ServeAdmin(h *health.Server) error {
+	c := s.Config.Admin
+	if c.Address == "" {
+		return nil
+	}
+	opts := []admin.Option{
+		admin.WithCollectors(s.collectors...),
+		admin.WithConfig(s.effectiveConfig),
+	}
+	if h != nil {
+		opts = append(opts, admin.WithHealth(h))
+	}
+	if c.TLS.CertFile != "" {
+		tc, err := c.TLS.tlsConfig()
+		if err != nil {
+			return fmt.Errorf("admin: %v", err)
+		}
+		opts = append(opts, admin.WithTLS(tc))
+	}
+	a := admin.New(c.Address, opts...)
+	go func() {
+		if err := a.ListenAndServe(); err != nil {
+			log.Printf("failed to serve admin endpoints: %v", err)
+		}
+	}()
+	return nil
+}
+
+// effectiveConfig returns the config of the server, with the settings changed
+// at run time through the admin endpoints.
+func (s *Server) effectiveConfig() interface{} {
+	c := *s.Config
+	c.Log.Level = logging.Default.Level().String()
+	return &c
+}
+
+// Serve serves the services registered so far on the address of the config,
+// along with the health and reflection services and the admin endpoints, until
+// the server is stopped by SIGINT or SIGTERM. The services are registered in
+// the registry of the config, if any.
+func (s *Server) Serve() error {
+	defer s.Close()
+
+	lis, err := net.Listen("tcp", s.Config.Server.Address) // Specify the port we want to use to listen for client requests
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	fmt.Printf("server listening at %v\n", lis.Addr())
+
+	// Register the services in the registry, if any, deregistered once shutting down
+	deregister, err := s.register(lis.Addr())
+	if err != nil {
+		return err
+	}
+	// Report the serving status of the services registered so far, NOT_SERVING once shutting down
+	h := healthcheck.Register(s.Server)
+	shutdown.GRPC(s.Server, h, s.Config.Server.ShutdownGrace, deregister)
+	// Describe the services to dynamic clients, such as grpccli
+	reflection.Register(s.Server)
+	if err := s.ServeAdmin(h); err != nil {
+		return err
+	}
+	return s.Server.Serve(lis)
+}
+
+// Close closes the files of the server, such as the trace file. Serve closes
+// them before returning, servers serving the gRPC server otherwise call it.
+func (s *Server) Close() {
+	for _, c := range s.closers {
+		c()
+	}
+}
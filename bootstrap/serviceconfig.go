@@ -0,0 +1,88 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"google.golang.org/grpc/balancer"
+)
+
+// DefaultServiceConfig is the service config of the clients without a
+// ServiceConfigFile: no per-method settings, so that the calls without a
+// deadline, such as the streams kept open by keepalive, never time out. The
+// clients of the examples have their timeouts in the service_config.json next
+// to them, see SourcePath, and service_config.example.json for the other settings.
+const DefaultServiceConfig = `{}`
+
+// SourcePath returns the path of name in the directory of the source file of
+// its caller, so that the examples find the files next to them wherever they
+// are run from, e.g. with go run from the root of the repository. It returns
+// name if the directory is unknown.
+func SourcePath(name string) string {
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		return name
+	}
+	return filepath.Join(filepath.Dir(file), name)
+}
+
+// serviceConfig returns the service config of gRPC of c, see
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md: the one of
+// the ServiceConfigFile, or DefaultServiceConfig, with the load balancing
+// settings of c unless it has its own. It is the default one, a resolver
+// returning its own service config, e.g. from an endpoints file or the DNS TXT
+// records, overrides it as a whole.
+func (c *ClientConfig) serviceConfig() (string, error) {
+	name, b := "DefaultServiceConfig", []byte(DefaultServiceConfig)
+	if c.ServiceConfigFile != "" {
+		var err error
+		if b, err = ioutil.ReadFile(c.ServiceConfigFile); err != nil {
+			return "", err
+		}
+		name = c.ServiceConfigFile
+	}
+	var sc map[string]interface{}
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return "", fmt.Errorf("invalid service config %s: %v", name, err)
+	}
+
+	if _, ok := sc["loadBalancingPolicy"]; !ok && c.LoadBalancing != "" {
+		sc["loadBalancingPolicy"] = c.LoadBalancing
+	}
+	if lb, ok := sc["loadBalancingPolicy"].(string); ok && balancer.Get(lb) == nil {
+		return "", fmt.Errorf("unknown load balancing policy %q", lb)
+	}
+	if _, ok := sc["healthCheckConfig"]; !ok && c.HealthCheck {
+		// The health of the whole server, the empty service name. Only the
+		// balancers other than pick_first, e.g. round_robin, check it
+		sc["healthCheckConfig"] = map[string]string{"serviceName": ""}
+	}
+	if hasRetryPolicy(sc) && !strings.EqualFold(os.Getenv("GRPC_GO_RETRY"), "on") {
+		log.Printf("the retryPolicy of the service config %s is ignored, unless GRPC_GO_RETRY=on", name)
+	}
+
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// hasRetryPolicy returns whether a method config of sc has a retryPolicy,
+// which this version of gRPC only applies when the environment variable
+// GRPC_GO_RETRY is "on".
+func hasRetryPolicy(sc map[string]interface{}) bool {
+	mcs, _ := sc["methodConfig"].([]interface{})
+	for _, mc := range mcs {
+		if mc, ok := mc.(map[string]interface{}); ok && mc["retryPolicy"] != nil {
+			return true
+		}
+	}
+	return false
+}
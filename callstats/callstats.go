@@ -0,0 +1,129 @@
+// Package callstats wraps grpc.ServerStream and grpc.ClientStream to collect
+// the statistics of streaming calls: messages and bytes in each direction,
+// time to the first message, total duration and final status. The logging and
+// metrics interceptors are built on top of them.
+package callstats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/status"
+)
+
+// Stats are the statistics of a call.
+type Stats struct {
+	Method string
+	Start  time.Time
+
+	MsgsSent      int64
+	MsgsReceived  int64
+	BytesSent     int64 // size of the serialized messages, without framing and compression
+	BytesReceived int64
+	FirstSent     time.Duration // time from Start to the first message sent, 0 if none
+	FirstReceived time.Duration // time from Start to the first message received, 0 if none
+
+	Duration time.Duration // time from Start to the end of the call
+	Err      error         // final status of the call, nil if it succeeded
+}
+
+// Code returns the status code of the call.
+func (s *Stats) Code() string {
+	return status.Code(s.Err).String()
+}
+
+// UnaryClient returns the statistics of a unary call of method made by a
+// client at start, which sent req, and received reply unless it failed with err.
+func UnaryClient(method string, start time.Time, req, reply interface{}, err error) Stats {
+	d := time.Since(start)
+	stats := Stats{Method: method, Start: start, Duration: d, Err: err}
+	stats.MsgsSent, stats.BytesSent = 1, size(req)
+	if err == nil {
+		stats.MsgsReceived, stats.BytesReceived, stats.FirstReceived = 1, size(reply), d
+	}
+	return stats
+}
+
+// UnaryServer returns the statistics of a unary call of method handled by a
+// server from start, which received req, and sent reply unless it failed with err.
+func UnaryServer(method string, start time.Time, req, reply interface{}, err error) Stats {
+	d := time.Since(start)
+	stats := Stats{Method: method, Start: start, Duration: d, Err: err}
+	stats.MsgsReceived, stats.BytesReceived = 1, size(req)
+	if err == nil {
+		stats.MsgsSent, stats.BytesSent, stats.FirstSent = 1, size(reply), d
+	}
+	return stats
+}
+
+// recorder records the statistics of a call, which may be updated by
+// concurrent SendMsg and RecvMsg calls.
+type recorder struct {
+	mu       sync.Mutex
+	stats    Stats
+	finished bool
+	done     chan struct{} // closed by finish
+	onFinish func(Stats)
+}
+
+func newRecorder(method string, onFinish func(Stats)) *recorder {
+	return &recorder{stats: Stats{Method: method, Start: time.Now()}, done: make(chan struct{}), onFinish: onFinish}
+}
+
+func size(m interface{}) int64 {
+	if pm, ok := m.(proto.Message); ok {
+		return int64(proto.Size(pm))
+	}
+	return 0
+}
+
+func (r *recorder) sent(m interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats.MsgsSent == 0 {
+		r.stats.FirstSent = time.Since(r.stats.Start)
+	}
+	r.stats.MsgsSent++
+	r.stats.BytesSent += size(m)
+}
+
+func (r *recorder) received(m interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats.MsgsReceived == 0 {
+		r.stats.FirstReceived = time.Since(r.stats.Start)
+	}
+	r.stats.MsgsReceived++
+	r.stats.BytesReceived += size(m)
+}
+
+// finish records the end of the call and calls onFinish, the first time only.
+func (r *recorder) finish(err error) {
+	r.mu.Lock()
+	if r.finished {
+		r.mu.Unlock()
+		return
+	}
+	r.finished = true
+	close(r.done)
+	r.stats.Duration = time.Since(r.stats.Start)
+	r.stats.Err = err
+	stats := r.stats
+	r.mu.Unlock()
+
+	if r.onFinish != nil {
+		r.onFinish(stats)
+	}
+}
+
+// snapshot returns the statistics so far.
+func (r *recorder) snapshot() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	if !r.finished {
+		stats.Duration = time.Since(stats.Start)
+	}
+	return stats
+}
@@ -0,0 +1,103 @@
+package callstats
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// ServerStream wraps grpc.ServerStream to collect the statistics of the stream.
+type ServerStream struct {
+	grpc.ServerStream
+	r *recorder
+}
+
+// NewServerStream wraps ss, a stream of method. The server interceptor must
+// call Finish with the error of the handler.
+func NewServerStream(ss grpc.ServerStream, method string) *ServerStream {
+	return &ServerStream{ServerStream: ss, r: newRecorder(method, nil)}
+}
+
+func (s *ServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.r.sent(m)
+	}
+	return err
+}
+
+func (s *ServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.r.received(m)
+	}
+	return err
+}
+
+// Finish records the end of the stream with the error of the handler, and
+// returns the statistics of the stream.
+func (s *ServerStream) Finish(err error) Stats {
+	s.r.finish(err)
+	return s.r.snapshot()
+}
+
+// Stats returns the statistics of the stream so far.
+func (s *ServerStream) Stats() Stats {
+	return s.r.snapshot()
+}
+
+// ClientStream wraps grpc.ClientStream to collect the statistics of the stream.
+type ClientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	r    *recorder
+}
+
+// NewClientStream wraps cs, a stream of method opened with ctx. onFinish is
+// called once with the statistics of the stream when it is over, which is when
+// RecvMsg returns an error (io.EOF meaning success), its only response for the
+// streams without server streaming, or when ctx is done for the streams that
+// the caller does not read to the end.
+func NewClientStream(ctx context.Context, cs grpc.ClientStream, desc *grpc.StreamDesc, method string, onFinish func(Stats)) *ClientStream {
+	s := &ClientStream{ClientStream: cs, desc: desc, r: newRecorder(method, onFinish)}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.r.finish(status.FromContextError(ctx.Err()).Err())
+		case <-s.r.done:
+		}
+	}()
+	return s
+}
+
+func (s *ClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.r.sent(m)
+	}
+	// An error of SendMsg is also returned by RecvMsg, with the status of the stream
+	return err
+}
+
+func (s *ClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.r.finish(nil)
+	case err != nil:
+		s.r.finish(err)
+	default:
+		s.r.received(m)
+		if !s.desc.ServerStreams {
+			s.r.finish(nil)
+		}
+	}
+	return err
+}
+
+// Stats returns the statistics of the stream so far.
+func (s *ClientStream) Stats() Stats {
+	return s.r.snapshot()
+}
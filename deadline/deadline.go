@@ -0,0 +1,158 @@
+// Package deadline provides interceptors that manage the deadline budget of the
+// calls: servers reject the calls that cannot finish within their remaining
+// deadline before doing any work, and cap the deadlines that are too long, and
+// clients keep a safety margin when they pass the remaining budget of a call on
+// to downstream calls.
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type options struct {
+	estimates map[string]time.Duration
+	max       time.Duration
+}
+
+// Option configures the server interceptors.
+type Option func(*options)
+
+// WithEstimate sets how long method (e.g. "/echo.Echo/UnaryEcho") needs to
+// finish. Calls with a shorter remaining deadline are rejected at once.
+func WithEstimate(method string, d time.Duration) Option {
+	return func(o *options) {
+		o.estimates[method] = d
+	}
+}
+
+// WithMax caps the deadline of the calls at d from their arrival, including the
+// calls that have no deadline.
+func WithMax(d time.Duration) Option {
+	return func(o *options) {
+		o.max = d
+	}
+}
+
+// Remaining returns the time left before the deadline of ctx, and false if ctx
+// has no deadline.
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// check applies the options to the context of a call of method.
+func (o *options) check(ctx context.Context, method string) (context.Context, context.CancelFunc, error) {
+	remaining, ok := Remaining(ctx)
+	if estimate := o.estimates[method]; ok && remaining < estimate {
+		return nil, nil, status.Errorf(codes.DeadlineExceeded, "remaining deadline %v is below the %v needed by %s", remaining.Round(time.Millisecond), estimate, method)
+	}
+	if o.max > 0 && (!ok || remaining > o.max) {
+		ctx, cancel := context.WithTimeout(ctx, o.max)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that enforces
+// the deadline budget of the calls.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := &options{estimates: make(map[string]time.Duration)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel, err := o.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that
+// enforces the deadline budget of the streams.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := &options{estimates: make(map[string]time.Duration)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel, err := o.check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream wraps grpc.ServerStream to replace its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// withMargin returns ctx with its deadline moved margin earlier, or an error if
+// no time would be left.
+func withMargin(ctx context.Context, method string, margin time.Duration) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	deadline = deadline.Add(-margin)
+	if time.Until(deadline) <= 0 {
+		return nil, nil, status.Errorf(codes.DeadlineExceeded, "no deadline budget left to call %s", method)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that passes
+// the remaining deadline of the call minus margin to the server, so that the
+// caller still has margin left to handle the response or the error. Calls are
+// failed without being sent when no time would be left.
+func UnaryClientInterceptor(margin time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, err := withMargin(ctx, method, margin)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that
+// passes the remaining deadline of the stream minus margin to the server.
+func StreamClientInterceptor(margin time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel, err := withMargin(ctx, method, margin)
+		if err != nil {
+			return nil, err
+		}
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// The stream is over when its context is done
+		go func() {
+			<-s.Context().Done()
+			cancel()
+		}()
+		return s, nil
+	}
+}
@@ -0,0 +1,174 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+	"gopkg.in/yaml.v2"
+)
+
+// FileScheme is the scheme of the targets made of the addresses of a file.
+const FileScheme = "file"
+
+// FilePollInterval is how often the files of the file:// targets are read
+// again, to push their changes to the clients.
+var FilePollInterval = time.Second
+
+func init() {
+	resolver.Register(fileBuilder{})
+}
+
+// Endpoints is the content of an endpoints file, e.g.
+//
+//	endpoints:
+//	  - address: localhost:50061
+//	    weight: 2
+//	    attributes:
+//	      zone: eu-west-1a
+//	  - address: localhost:50062
+//	service_config: |
+//	  {
+//	    "loadBalancingPolicy": "round_robin",
+//	    "healthCheckConfig": {"serviceName": ""},
+//	    "methodConfig": [{"name": [{"service": "math.Math", "method": "Sum"}], "timeout": "2s"}]
+//	  }
+//
+// The service config, if any, replaces the one of the clients as a whole, so
+// it should set the load balancing policy too, pick_first being the default
+// of gRPC. Once the clients got one, removing it from the file keeps it until
+// they restart.
+type Endpoints struct {
+	Endpoints     []Endpoint `yaml:"endpoints"`
+	ServiceConfig string     `yaml:"service_config"` // the JSON of the service config of gRPC
+}
+
+// Endpoint is an address of an endpoints file.
+type Endpoint struct {
+	Address    string            `yaml:"address"`
+	Weight     int               `yaml:"weight"` // 1 if 0
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// ParseEndpoints parses the content of an endpoints file.
+func ParseEndpoints(b []byte) (*Endpoints, error) {
+	var e Endpoints
+	if err := yaml.UnmarshalStrict(b, &e); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for i, ep := range e.Endpoints {
+		switch {
+		case ep.Address == "":
+			return nil, fmt.Errorf("endpoint %d: no address", i)
+		case ep.Weight < 0:
+			return nil, fmt.Errorf("endpoint %s: negative weight %d", ep.Address, ep.Weight)
+		case seen[ep.Address]:
+			return nil, fmt.Errorf("endpoint %s: duplicate address", ep.Address)
+		}
+		seen[ep.Address] = true
+	}
+	if e.ServiceConfig != "" {
+		var sc map[string]interface{}
+		if err := json.Unmarshal([]byte(e.ServiceConfig), &sc); err != nil {
+			return nil, fmt.Errorf("service_config: %v", err)
+		}
+	}
+	return &e, nil
+}
+
+// fileBuilder builds the resolvers of the file:// targets.
+type fileBuilder struct{}
+
+func (fileBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	// file:///etc/endpoints.yaml is absolute, file://./endpoints.yaml relative
+	path := target.Authority + "/" + target.Endpoint
+	r := &fileResolver{
+		path:       path,
+		cc:         cc,
+		resolveNow: make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	// Fail the Dial if the file is missing or invalid, the later errors keep
+	// the addresses read last
+	if err := r.update(); err != nil {
+		return nil, fmt.Errorf("discovery: %v", err)
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (fileBuilder) Scheme() string {
+	return FileScheme
+}
+
+// fileResolver pushes the addresses and the service config of a file to a
+// ClientConn.
+type fileResolver struct {
+	path       string
+	cc         resolver.ClientConn
+	content    []byte // read last
+	addrs      addresses
+	resolveNow chan struct{}
+	done       chan struct{}
+}
+
+// watch reads the file again on every tick or ResolveNow, until Close.
+func (r *fileResolver) watch() {
+	t := time.NewTicker(FilePollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-r.resolveNow:
+		case <-r.done:
+			return
+		}
+		if err := r.update(); err != nil {
+			log.Printf("discovery: keeping the addresses read last: %v", err)
+		}
+	}
+}
+
+// update pushes the addresses and the service config of the file if it
+// changed.
+func (r *fileResolver) update() error {
+	b, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	if r.content != nil && bytes.Equal(b, r.content) {
+		return nil
+	}
+	e, err := ParseEndpoints(b)
+	if err != nil {
+		// Do not log the same error again until the file changes
+		r.content = b
+		return fmt.Errorf("invalid endpoints file %s: %v", r.path, err)
+	}
+	r.content = b
+
+	var addrs []string
+	mds := make(map[string]*Metadata)
+	for _, ep := range e.Endpoints {
+		addrs = append(addrs, ep.Address)
+		mds[ep.Address] = &Metadata{Weight: ep.Weight, Attributes: ep.Attributes}
+	}
+	r.cc.UpdateState(resolver.State{Addresses: r.addrs.build(addrs, mds), ServiceConfig: e.ServiceConfig})
+	return nil
+}
+
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOption) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *fileResolver) Close() {
+	close(r.done)
+}
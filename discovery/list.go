@@ -0,0 +1,74 @@
+// Package discovery registers the name resolvers of the clients, which turn
+// the target of grpc.Dial into the addresses of the servers the calls are
+// balanced across:
+//
+//	list:///localhost:50051,localhost:50052   a fixed list of addresses
+//	file:///path/to/endpoints.yaml            the addresses and the service config of a file, watched for changes
+//	file://./endpoints.yaml                   the same, relative to the working directory
+//	registry://localhost:50050/math.Math      the instances of a service in the registry
+//
+// The dns:/// resolver of gRPC resolves a name to all of its addresses, e.g.
+// dns:///math.example.com:50051, and its service config to the grpc_config=
+// TXT record of _grpc_config.math.example.com.
+//
+// The file and registry resolvers set the Metadata of the addresses to a
+// *Metadata, with the weight and the attributes of the addresses.
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// ListScheme is the scheme of the targets made of a list of addresses.
+const ListScheme = "list"
+
+func init() {
+	resolver.Register(listBuilder{})
+}
+
+// ListTarget returns the target of grpc.Dial resolving to addrs.
+func ListTarget(addrs ...string) string {
+	return ListScheme + ":///" + strings.Join(addrs, ",")
+}
+
+// SplitList returns the addresses of a comma-separated list, e.g.
+// "localhost:50051, localhost:50052", and whether there are several of them.
+func SplitList(s string) ([]string, bool) {
+	var addrs []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs, len(addrs) > 1
+}
+
+// listBuilder builds the resolvers of the list:/// targets.
+type listBuilder struct{}
+
+func (listBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	addrs, _ := SplitList(target.Endpoint)
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("discovery: no address in target %q", target.Endpoint)
+	}
+	state := resolver.State{}
+	for _, a := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: a})
+	}
+	cc.UpdateState(state)
+	return listResolver{}, nil
+}
+
+func (listBuilder) Scheme() string {
+	return ListScheme
+}
+
+// listResolver has nothing to do: the addresses of a list never change.
+type listResolver struct{}
+
+func (listResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+func (listResolver) Close() {}
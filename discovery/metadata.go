@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// Metadata is the Metadata of the addresses resolved by the file and registry
+// resolvers, for the balancers.
+type Metadata struct {
+	Weight     int               // the relative share of the calls, 1 if 0
+	Attributes map[string]string // e.g. zone: eu-west-1a
+}
+
+// MetadataOf returns the metadata of addr, nil if it has none.
+func MetadataOf(addr resolver.Address) *Metadata {
+	md, _ := addr.Metadata.(*Metadata)
+	return md
+}
+
+// WeightOf returns the weight of addr, 1 by default.
+func WeightOf(addr resolver.Address) int {
+	if md := MetadataOf(addr); md != nil && md.Weight > 0 {
+		return md.Weight
+	}
+	return 1
+}
+
+// addresses builds the addresses pushed to a ClientConn. The balancers compare
+// the addresses, Metadata included, to keep the connections of the addresses
+// that did not change: the Metadata of an address is the same pointer as long
+// as it is equal.
+type addresses struct {
+	previous map[string]*Metadata
+}
+
+// build returns the addresses of the metadata of each address, in order.
+func (a *addresses) build(addrs []string, mds map[string]*Metadata) []resolver.Address {
+	current := make(map[string]*Metadata, len(addrs))
+	var resolved []resolver.Address
+	for _, addr := range addrs {
+		md := mds[addr]
+		if old, ok := a.previous[addr]; ok && reflect.DeepEqual(old, md) {
+			md = old
+		}
+		current[addr] = md
+		resolved = append(resolved, resolver.Address{Addr: addr, Metadata: md})
+	}
+	a.previous = current
+	return resolved
+}
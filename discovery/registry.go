@@ -0,0 +1,134 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	pb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// RegistryScheme is the scheme of the targets made of the instances of a
+// service in the registry, e.g. registry://localhost:50050/math.Math.
+const RegistryScheme = "registry"
+
+// RegistryRetryInterval is how long the registry resolvers wait before
+// watching again once the registry is unreachable.
+var RegistryRetryInterval = time.Second
+
+func init() {
+	resolver.Register(registryBuilder{})
+}
+
+// RegistryTarget returns the target of grpc.Dial resolving to the instances
+// of service in the registry at addr.
+func RegistryTarget(addr, service string) string {
+	return RegistryScheme + "://" + addr + "/" + service
+}
+
+// registryBuilder builds the resolvers of the registry:// targets.
+type registryBuilder struct{}
+
+func (registryBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	if target.Authority == "" || target.Endpoint == "" {
+		return nil, fmt.Errorf("discovery: want registry://<registry address>/<service>, got the authority %q and the service %q", target.Authority, target.Endpoint)
+	}
+	// The registry is a development tool, served without TLS
+	conn, err := grpc.Dial(target.Authority, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("discovery: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &registryResolver{
+		service: target.Endpoint,
+		client:  pb.NewRegistryClient(conn),
+		conn:    conn,
+		cc:      cc,
+		cancel:  cancel,
+	}
+	go r.watch(ctx)
+	return r, nil
+}
+
+func (registryBuilder) Scheme() string {
+	return RegistryScheme
+}
+
+// registryResolver pushes the instances of a service in the registry to a
+// ClientConn.
+type registryResolver struct {
+	service string
+	client  pb.RegistryClient
+	conn    *grpc.ClientConn
+	cc      resolver.ClientConn
+	addrs   addresses
+	cancel  context.CancelFunc
+}
+
+// watch watches the instances of the service until ctx is done, watching
+// again when the stream breaks. The addresses received last are kept
+// meanwhile.
+func (r *registryResolver) watch(ctx context.Context) {
+	for {
+		err := r.watchOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("discovery: failed to watch %s, retrying in %v: %v", r.service, RegistryRetryInterval, err)
+		select {
+		case <-time.After(RegistryRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchOnce pushes the instances of a Watch call, until the stream breaks.
+func (r *registryResolver) watchOnce(ctx context.Context) error {
+	// WaitForReady: wait for the registry to start, rather than fail at once
+	stream, err := r.client.Watch(ctx, &pb.WatchRequest{Service: r.service}, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+	// The first response has all the instances, the next ones the changes
+	instances := make(map[string]*pb.Instance)
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		for _, e := range resp.Events {
+			switch e.Type {
+			case pb.Event_ADD:
+				instances[e.Instance.GetAddress()] = e.Instance
+			case pb.Event_REMOVE:
+				delete(instances, e.Instance.GetAddress())
+			}
+		}
+		r.update(instances)
+	}
+}
+
+// update pushes the addresses of instances.
+func (r *registryResolver) update(instances map[string]*pb.Instance) {
+	var addrs []string
+	mds := make(map[string]*Metadata)
+	for addr, inst := range instances {
+		addrs = append(addrs, addr)
+		mds[addr] = &Metadata{Weight: int(inst.Weight), Attributes: inst.Metadata}
+	}
+	sort.Strings(addrs)
+	r.cc.UpdateState(resolver.State{Addresses: r.addrs.build(addrs, mds)})
+}
+
+// ResolveNow does nothing: the changes are pushed by the registry.
+func (r *registryResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+func (r *registryResolver) Close() {
+	r.cancel()
+	r.conn.Close()
+}
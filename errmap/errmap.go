@@ -0,0 +1,195 @@
+// Package errmap maps the errors declared by domain packages to gRPC statuses on
+// the server side, and the statuses back to the same Go errors on the client side.
+//
+// A domain package registers each of its sentinel errors with Register and each
+// of its error types with RegisterAs, usually in an init function. Handlers then
+// simply return domain errors (possibly wrapped with fmt.Errorf("...: %w", err))
+// and the server interceptors turn them into statuses carrying an ErrorInfo.
+// Errors that are not registered are logged and returned as codes.Internal,
+// without their text.
+package errmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/wangy8961/grpc-go-tutorial/statusdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Mapping describes how a domain error is carried by a gRPC status.
+type Mapping struct {
+	// Code is the status code of the error.
+	Code codes.Code
+	// Domain and Reason identify the error in the ErrorInfo detail of the status.
+	// Together they must be unique within a Registry.
+	Domain string
+	Reason string
+	// Message is the status message. If empty, the text of the error is used.
+	Message string
+
+	// Encode returns the ErrorInfo metadata of an error of a type registered with
+	// RegisterAs. It receives the error found by errors.As.
+	Encode func(err error) map[string]string
+	// Decode rebuilds an error of a type registered with RegisterAs from the
+	// ErrorInfo metadata received by a client.
+	Decode func(metadata map[string]string) error
+}
+
+type entry struct {
+	Mapping
+	sentinel error        // for Register
+	target   reflect.Type // for RegisterAs, a pointer to the error type
+}
+
+// match returns the error in err's chain that matches the entry.
+func (e *entry) match(err error) (error, bool) {
+	if e.sentinel != nil {
+		return e.sentinel, errors.Is(err, e.sentinel)
+	}
+	p := reflect.New(e.target)
+	if !errors.As(err, p.Interface()) {
+		return nil, false
+	}
+	return p.Elem().Interface().(error), true
+}
+
+// Registry holds the mappings of domain errors.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []*entry
+}
+
+// NewRegistry returns a Registry that maps the context errors to
+// codes.Canceled and codes.DeadlineExceeded.
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.Register(context.Canceled, Mapping{Code: codes.Canceled, Domain: "context", Reason: "CANCELED"})
+	r.Register(context.DeadlineExceeded, Mapping{Code: codes.DeadlineExceeded, Domain: "context", Reason: "DEADLINE_EXCEEDED"})
+	return r
+}
+
+// Register maps the sentinel error target, and every error wrapping it.
+func (r *Registry) Register(target error, m Mapping) {
+	r.add(&entry{Mapping: m, sentinel: target})
+}
+
+// RegisterAs maps every error whose chain contains an error of the type of
+// target, which is usually a typed nil such as (*matherr.OverflowError)(nil).
+// m.Decode must be set so that clients can rebuild the error.
+func (r *Registry) RegisterAs(target error, m Mapping) {
+	if m.Decode == nil {
+		panic(fmt.Sprintf("errmap: RegisterAs(%T) without a Decode function", target))
+	}
+	r.add(&entry{Mapping: m, target: reflect.TypeOf(target)})
+}
+
+func (r *Registry) add(e *entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, old := range r.entries {
+		if old.Domain == e.Domain && old.Reason == e.Reason {
+			panic(fmt.Sprintf("errmap: reason %s/%s registered twice", e.Domain, e.Reason))
+		}
+	}
+	r.entries = append(r.entries, e)
+}
+
+// ToStatus converts err into a status. Errors that already carry a status, such
+// as the ones built with status.Errorf, are returned unchanged. Unmapped errors
+// are logged and converted into a codes.Internal status that hides their text.
+func (r *Registry) ToStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if se, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return se.GRPCStatus()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, e := range r.entries {
+		matched, ok := e.match(err)
+		if !ok {
+			continue
+		}
+		msg := e.Message
+		if msg == "" {
+			msg = err.Error()
+		}
+		info := &statusdetails.ErrorInfo{Domain: e.Domain, Reason: e.Reason}
+		if e.Encode != nil {
+			info.Metadata = e.Encode(matched)
+		}
+		st, detailsErr := status.New(e.Code, msg).WithDetails(info)
+		if detailsErr != nil {
+			return status.New(e.Code, msg)
+		}
+		return st
+	}
+
+	log.Printf("errmap: unmapped error returned as Internal: %v", err)
+	return status.New(codes.Internal, "internal error")
+}
+
+// FromError converts an error returned by a gRPC call back into the domain error
+// named by its ErrorInfo. The returned error matches the domain error with
+// errors.Is and errors.As, and still carries the status for status.FromError.
+// Errors without a registered ErrorInfo are returned unchanged.
+func (r *Registry) FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var info statusdetails.ErrorInfo
+	if !statusdetails.Find(err, &info) {
+		return err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, e := range r.entries {
+		if e.Domain != info.GetDomain() || e.Reason != info.GetReason() {
+			continue
+		}
+		domainErr := e.sentinel
+		if e.Decode != nil {
+			domainErr = e.Decode(info.GetMetadata())
+		}
+		return &Error{err: domainErr, status: status.Convert(err)}
+	}
+	return err
+}
+
+// Error is a domain error received from a server.
+type Error struct {
+	err    error
+	status *status.Status
+}
+
+func (e *Error) Error() string { return e.status.Message() }
+
+// Unwrap returns the domain error.
+func (e *Error) Unwrap() error { return e.err }
+
+// GRPCStatus returns the status the error was received with.
+func (e *Error) GRPCStatus() *status.Status { return e.status }
+
+// DefaultRegistry is the Registry used by the package-level functions and interceptors.
+var DefaultRegistry = NewRegistry()
+
+// Register maps a sentinel error in DefaultRegistry.
+func Register(target error, m Mapping) { DefaultRegistry.Register(target, m) }
+
+// RegisterAs maps an error type in DefaultRegistry.
+func RegisterAs(target error, m Mapping) { DefaultRegistry.RegisterAs(target, m) }
+
+// ToStatus converts err into a status with DefaultRegistry.
+func ToStatus(err error) *status.Status { return DefaultRegistry.ToStatus(err) }
+
+// FromError converts err back into a domain error with DefaultRegistry.
+func FromError(err error) error { return DefaultRegistry.FromError(err) }
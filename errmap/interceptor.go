@@ -0,0 +1,92 @@
+package errmap
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor returns a server-side unary interceptor that converts
+// the errors of the handler with ToStatus.
+func (r *Registry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		m, err := handler(ctx, req)
+		if err != nil {
+			return m, r.ToStatus(err).Err()
+		}
+		return m, nil
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that
+// converts the errors of the handler with ToStatus.
+func (r *Registry) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return r.ToStatus(err).Err()
+		}
+		return nil
+	}
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that converts
+// the errors of the call with FromError.
+func (r *Registry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return r.FromError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that
+// converts the errors of the stream with FromError.
+func (r *Registry) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, r.FromError(err)
+		}
+		return &clientStream{ClientStream: s, r: r}, nil
+	}
+}
+
+// clientStream wraps grpc.ClientStream to convert the errors of the stream.
+type clientStream struct {
+	grpc.ClientStream
+	r *Registry
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	return s.convert(s.ClientStream.SendMsg(m))
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	return s.convert(s.ClientStream.RecvMsg(m))
+}
+
+func (s *clientStream) convert(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return s.r.FromError(err)
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that uses DefaultRegistry.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return DefaultRegistry.UnaryServerInterceptor()
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that uses DefaultRegistry.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return DefaultRegistry.StreamServerInterceptor()
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that uses DefaultRegistry.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return DefaultRegistry.UnaryClientInterceptor()
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that uses DefaultRegistry.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return DefaultRegistry.StreamClientInterceptor()
+}
@@ -4,42 +4,32 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"log"
-	"net"
 	"strings"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
-// server is used to implement echopb.EchoServer.
-type server struct{}
-
-/*
-func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UnaryEcho not implemented")
-}
-*/
-func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
-	fmt.Printf("--- gRPC Unary RPC ---\n")
-	fmt.Printf("request received: %v\n", req)
-
+// authorize checks the credentials in the "Authorization" header of the call.
+func authorize(ctx context.Context) error {
 	// md 的值类似于: map[:authority:[192.168.40.123:50051] authorization:[Bearer some-secret-token] content-type:[application/grpc] user-agent:[grpc-go/1.20.1]]
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, status.Errorf(codes.InvalidArgument, "missing metadata")
+		return status.Errorf(codes.InvalidArgument, "missing metadata")
 	}
 	// fmt.Printf("Type of 'metadata.MD' is %T, and its value is %v \n", md, md)
 
 	// 1. 判断是否存在 authorization 请求头
 	authorization, ok := md["authorization"]
 	if !ok {
-		return nil, status.Errorf(codes.Unauthenticated, `missing "Authorization" header`)
+		return status.Errorf(codes.Unauthenticated, `missing "Authorization" header`)
 	}
 	// fmt.Printf("Type of 'authorization' is %T, and its value is %v \n", authorization, authorization)
 
@@ -47,50 +37,65 @@ func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoRe
 
 	// 2. 如果存在 authorization 请求头的话，则 md["authorization"] 是一个 []string
 	if !strings.HasPrefix(authorization[0], prefix) {
-		return nil, status.Errorf(codes.Unauthenticated, `missing "Bearer " prefix in "Authorization" header`)
+		return status.Errorf(codes.Unauthenticated, `missing "Bearer " prefix in "Authorization" header`)
 	}
 
 	// 3. 验证 token 是否一致
 	if token := strings.TrimPrefix(authorization[0], prefix); token != "some-secret-token" {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid token")
+		return status.Errorf(codes.Unauthenticated, "invalid token")
 	}
 
-	return &pb.EchoResponse{Message: req.GetMessage()}, nil
-}
-
-func (s *server) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ServerStreamingEcho not implemented")
+	return nil
 }
 
-func (s *server) ClientStreamingEcho(stream pb.Echo_ClientStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ClientStreamingEcho not implemented")
+// server-side unary interceptor (For Authentication)
+func unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	// The health probes have no credentials
+	if healthcheck.IsHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
 }
 
-func (s *server) BidirectionalStreamingEcho(stream pb.Echo_BidirectionalStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method BidirectionalStreamingEcho not implemented")
+// server-side streaming interceptor (For Authentication)
+func streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	// The health probes have no credentials
+	if healthcheck.IsHealthMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	if err := authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
 }
 
 func main() {
-	port := flag.Int("port", 50051, "the port to serve on")
-	certFile := flag.String("certfile", "server.crt", "Server certificate")
-	keyFile := flag.String("keyfile", "server.key", "Server private key")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.TLS.CertFile = "server.crt"
+	cfg.Server.TLS.KeyFile = "server.key"
+	cfg.Server.Interceptors.Logging = true // Log one JSON line per call
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port)) // Specify the port we want to use to listen for client requests
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
-	fmt.Printf("server listening at %v\n", lis.Addr())
 
-	creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
+	s, err := bootstrap.NewServer(cfg,
+		// Every kind of RPC requires the credentials
+		bootstrap.WithUnaryInterceptors(unaryAuthInterceptor),
+		bootstrap.WithStreamInterceptors(streamAuthInterceptor),
+	) // Create an instance of the gRPC server
 	if err != nil {
-		log.Fatalf("failed to load certificates: %v", err)
+		log.Fatalf("failed to create server: %v", err)
 	}
 
-	s := grpc.NewServer(grpc.Creds(creds)) // Create an instance of the gRPC server
-
-	pb.RegisterEchoServer(s, &server{})  // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil { // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	pb.RegisterEchoServer(s.Server, &echoserver.Server{}) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {                     // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
@@ -8,8 +8,8 @@ import (
 	"log"
 	"time"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -32,11 +32,17 @@ func recvMessage(stream pb.Echo_BidirectionalStreamingEchoClient, wantErrCode co
 }
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
+	cfg := bootstrap.DefaultConfig()
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, grpc.WithInsecure()) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	conn, err := bootstrap.Dial(&cfg.Client) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -1,4 +1,13 @@
 // Package main implements a client for Echo service.
+//
+// The deadline of a call is the timeout of UnaryEcho in the service config of
+// its connection. The client calls the server twice, on two connections: the
+// first one with service_config.json, whose 5s are enough for the 3s the
+// server takes, the second one with service_config_short.json, whose 1s are
+// exceeded. Both files are found next to this one, unless -service-config and
+// -short-service-config are set:
+//
+//	go run ./features/deadline/client
 package main
 
 import (
@@ -6,24 +15,19 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
-	"time"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func unaryCallWithDeadline(c pb.EchoClient, timeout time.Duration, msg string) {
+func unaryCallWithDeadline(c pb.EchoClient, msg string) {
 	fmt.Printf("--- gRPC Unary RPC Call ---\n")
-	// Make unary RPC
-	// ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(timeout))
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
+	// Make unary RPC, gRPC sets its deadline to the timeout of the service config.
+	// A shorter deadline of ctx, e.g. context.WithTimeout(context.Background(), time.Second), would win
 	req := &pb.EchoRequest{Message: msg}
-	resp, err := c.UnaryEcho(ctx, req)
+	resp, err := c.UnaryEcho(context.Background(), req)
 	if err != nil {
 		// Error Handling
 		errStatus, ok := status.FromError(err)
@@ -42,28 +46,44 @@ func unaryCallWithDeadline(c pb.EchoClient, timeout time.Duration, msg string) {
 }
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.ServiceConfigFile = bootstrap.SourcePath("service_config.json")
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	shortServiceConfig := flag.String("short-service-config", bootstrap.SourcePath("service_config_short.json"), "the service config of the second connection, with a shorter timeout")
 	flag.Parse()
 
-	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, grpc.WithInsecure()) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	// Set up a connection to the server, and another one with the shorter timeout of service_config_short.json
+	conn, err := bootstrap.Dial(&cfg.Client) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 	defer conn.Close()
 
+	short := cfg.Client
+	short.ServiceConfigFile = *shortServiceConfig
+	shortConn, err := bootstrap.Dial(&short)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer shortConn.Close()
+
 	c := pb.NewEchoClient(conn) // Once the gRPC channel is setup, we need a client stub to perform RPCs. We get this using the NewEchoClient method provided in the pb package we generated from our .proto.
 
 	// Contact the server and print out its response.
 	msg := "Madman"
-	if len(os.Args) > 1 {
-		msg = os.Args[1]
+	if flag.NArg() > 0 {
+		msg = flag.Arg(0)
 	}
 
 	// 1. succeed
-	unaryCallWithDeadline(c, 5*time.Second, msg)
+	unaryCallWithDeadline(c, msg)
 	fmt.Println()
 
 	// 2. failed
-	unaryCallWithDeadline(c, 1*time.Second, msg)
+	unaryCallWithDeadline(pb.NewEchoClient(shortConn), msg)
 }
@@ -6,59 +6,116 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"net"
 	"time"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/deadline"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/features/simulate"
+	"github.com/wangy8961/grpc-go-tutorial/logging"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+// work is how long UnaryEcho takes to process a request, unless set by the simulation.
+const work = 3 * time.Second
+
 // server is used to implement echopb.EchoServer.
-type server struct{}
+type server struct {
+	echoserver.Server
+
+	downstream pb.EchoClient // forward the requests to it if not nil
+}
 
 func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
 	fmt.Printf("--- gRPC Unary RPC ---\n")
 	fmt.Printf("request received: %v\n", req)
-
-	// Suppose it takes a long time to process (3 seconds)
-	for i := 0; i < 3; i++ {
-		if ctx.Err() == context.Canceled {
-			// the client canceled the request
-			fmt.Println("The client canceled the request!")
-			return nil, status.Errorf(codes.Canceled, "The client canceled the request")
-		}
-		time.Sleep(1 * time.Second)
+	if remaining, ok := deadline.Remaining(ctx); ok {
+		fmt.Printf("remaining deadline: %v\n", remaining.Round(time.Millisecond))
 	}
-	return &pb.EchoResponse{Message: req.GetMessage()}, nil
-}
 
-func (s *server) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ServerStreamingEcho not implemented")
-}
-
-func (s *server) ClientStreamingEcho(stream pb.Echo_ClientStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ClientStreamingEcho not implemented")
-}
-
-func (s *server) BidirectionalStreamingEcho(stream pb.Echo_BidirectionalStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method BidirectionalStreamingEcho not implemented")
+	// The work was simulated by the simulate interceptor, which returns early
+	// when the client cancels the request or its deadline is exceeded
+	if s.downstream == nil {
+		return &pb.EchoResponse{Message: req.GetMessage()}, nil
+	}
+	// The downstream call gets the remaining deadline of this one, minus a safety margin
+	resp, err := s.downstream.UnaryEcho(ctx, req)
+	if err != nil {
+		fmt.Printf("downstream call failed: %v\n", err)
+		return nil, err
+	}
+	return resp, nil
 }
 
 func main() {
-	port := flag.Int("port", 50051, "the port to serve on")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Admin.Address = ":9090"
+	cfg.Server.Interceptors = bootstrap.InterceptorsConfig{Tracing: true, Logging: true, Metrics: true}
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	estimate := flag.Duration("estimate", work, "the time needed by UnaryEcho, including the downstream call")
+	maxDeadline := flag.Duration("max-deadline", 10*time.Second, "the maximum deadline of the calls")
+	downstream := flag.String("downstream", "", "the address of an Echo server to forward the requests to")
+	simulation := flag.String("simulate", "", "the simulation config file, by default UnaryEcho takes 3 seconds")
+	margin := flag.Duration("margin", 200*time.Millisecond, "the part of the deadline kept for ourselves when calling downstream")
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port)) // Specify the port we want to use to listen for client requests
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	sim := simulate.Config{
+		"UnaryEcho":           {Latency: simulate.Latency{Kind: "fixed", A: work}},
+		"ServerStreamingEcho": {Interval: time.Second}, // paces the responses of echoserver.Server
+	}
+	if *simulation != "" {
+		c, err := simulate.Load(*simulation)
+		if err != nil {
+			log.Fatalf("failed to load simulation: %v", err)
+		}
+		sim = c
+	}
+
+	// Reject the calls that cannot finish in time before doing any work
+	deadlineOpts := []deadline.Option{
+		deadline.WithEstimate("/echo.Echo/UnaryEcho", *estimate),
+		deadline.WithMax(*maxDeadline),
+	}
+	s, err := bootstrap.NewServer(cfg,
+		bootstrap.WithUnaryInterceptors(
+			deadline.UnaryServerInterceptor(deadlineOpts...),
+			simulate.UnaryServerInterceptor(sim),
+		),
+		bootstrap.WithStreamInterceptors(
+			deadline.StreamServerInterceptor(deadlineOpts...),
+			simulate.StreamServerInterceptor(sim),
+		),
+	) // Create an instance of the gRPC server
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to create server: %v", err)
+	}
+
+	srv := &server{}
+	if *downstream != "" {
+		// The trace context is propagated to the downstream server even if the spans are not exported
+		conn, err := grpc.Dial(*downstream, grpc.WithInsecure(),
+			grpc.WithChainUnaryInterceptor(
+				s.Tracer.UnaryClientInterceptor(),
+				// The downstream call carries the request ID of the call being handled
+				logging.UnaryClientInterceptor(),
+				deadline.UnaryClientInterceptor(*margin),
+			),
+		)
+		if err != nil {
+			log.Fatalf("did not connect: %v", err)
+		}
+		defer conn.Close()
+		srv.downstream = pb.NewEchoClient(conn)
 	}
-	fmt.Printf("server listening at %v\n", lis.Addr())
 
-	s := grpc.NewServer()                // Create an instance of the gRPC server
-	pb.RegisterEchoServer(s, &server{})  // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil { // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	pb.RegisterEchoServer(s.Server, srv) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {    // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
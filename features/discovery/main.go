@@ -0,0 +1,241 @@
+// Package main resolves the addresses of three Echo servers running in the
+// same process with the resolvers of package discovery, and checks that the
+// calls follow the changes:
+//
+//  1. file://: the client balances the calls across the addresses of an
+//     endpoints file, and follows the file when it is rewritten;
+//  2. registry://: the client balances the calls across the instances
+//     registered in a registry, and follows the registrations.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/discovery"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	"github.com/wangy8961/grpc-go-tutorial/registry"
+	registrypb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+)
+
+// server answers UnaryEcho quietly, unlike echoserver.Server.
+type server struct {
+	echoserver.Server
+}
+
+func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// startEcho starts an Echo server on a random port and returns its address.
+func startEcho() (string, error) {
+	s, err := bootstrap.NewServer(bootstrap.DefaultConfig())
+	if err != nil {
+		return "", err
+	}
+	pb.RegisterEchoServer(s.Server, &server{})
+	healthcheck.Register(s.Server)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	go s.Server.Serve(lis)
+	return lis.Addr().String(), nil
+}
+
+// printer is a resolver.ClientConn printing the addresses pushed by a
+// resolver, with their weight and attributes.
+type printer struct {
+	updated chan struct{}
+}
+
+func (p *printer) UpdateState(s resolver.State) {
+	for _, a := range s.Addresses {
+		md := discovery.MetadataOf(a)
+		fmt.Printf("  %s weight %d attributes %v\n", a.Addr, discovery.WeightOf(a), md.Attributes)
+	}
+	p.updated <- struct{}{}
+}
+
+func (p *printer) NewAddress(addrs []resolver.Address) {
+	p.UpdateState(resolver.State{Addresses: addrs})
+}
+
+func (p *printer) NewServiceConfig(string) {}
+
+// served makes n calls and returns the addresses serving them, sorted.
+func served(c pb.EchoClient, n int) ([]string, int) {
+	seen := make(map[string]bool)
+	failed := 0
+	for i := 0; i < n; i++ {
+		var p peer.Peer
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "madmalls"}, grpc.Peer(&p))
+		cancel()
+		if err != nil {
+			failed++
+			continue
+		}
+		seen[p.Addr.String()] = true
+	}
+	var addrs []string
+	for a := range seen {
+		addrs = append(addrs, a)
+	}
+	sort.Strings(addrs)
+	return addrs, failed
+}
+
+// waitServed makes rounds of n calls until exactly want serve them, within
+// timeout, and returns the addresses serving the last round.
+func waitServed(c pb.EchoClient, n int, timeout time.Duration, want ...string) ([]string, bool) {
+	sort.Strings(want)
+	deadline := time.Now().Add(timeout)
+	for {
+		addrs, failed := served(c, n)
+		if failed == 0 && strings.Join(addrs, ",") == strings.Join(want, ",") {
+			return addrs, true
+		}
+		if time.Now().After(deadline) {
+			return addrs, false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// writeEndpoints writes an endpoints file with addrs, the weight of each being
+// its position in the list, in the zone a or b.
+func writeEndpoints(path string, addrs ...string) error {
+	var b strings.Builder
+	b.WriteString("endpoints:\n")
+	for i, a := range addrs {
+		fmt.Fprintf(&b, "  - address: %s\n    weight: %d\n    attributes:\n      zone: %c\n", a, i+1, 'a'+i%2)
+	}
+	// Write then rename, so that the resolver never reads a partial file
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func main() {
+	calls := flag.Int("calls", 30, "the number of calls of each round")
+	flag.Parse()
+	discovery.FilePollInterval = 100 * time.Millisecond
+
+	var addrs []string
+	for i := 0; i < 3; i++ {
+		addr, err := startEcho()
+		if err != nil {
+			log.Fatalf("failed to start server: %v", err)
+		}
+		addrs = append(addrs, addr)
+	}
+
+	var problems []string
+	check := func(c pb.EchoClient, title string, want ...string) {
+		got, ok := waitServed(c, *calls, 5*time.Second, want...)
+		fmt.Printf("%s\n  served by: %s\n", title, strings.Join(got, ", "))
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: want %s", title, strings.Join(want, ", ")))
+		}
+	}
+
+	// 1. file://
+	dir, err := ioutil.TempDir("", "discovery")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "endpoints.yaml")
+	if err := writeEndpoints(path, addrs[0], addrs[1]); err != nil {
+		log.Fatalf("failed to write endpoints: %v", err)
+	}
+	target := "file://" + path
+
+	fmt.Printf("--- %s resolves to ---\n", target)
+	p := &printer{updated: make(chan struct{}, 1)}
+	r, err := resolver.Get(discovery.FileScheme).Build(resolver.Target{Scheme: discovery.FileScheme, Endpoint: strings.TrimPrefix(path, "/")}, p, resolver.BuildOption{})
+	if err != nil {
+		log.Fatalf("failed to resolve %s: %v", target, err)
+	}
+	<-p.updated
+	r.Close()
+
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.Address = target
+	conn, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+	c := pb.NewEchoClient(conn)
+	check(c, "--- 1.1 The endpoints of the file ---", addrs[0], addrs[1])
+	if err := writeEndpoints(path, addrs[1], addrs[2]); err != nil {
+		log.Fatalf("failed to write endpoints: %v", err)
+	}
+	check(c, "--- 1.2 The endpoints of the rewritten file ---", addrs[1], addrs[2])
+
+	// 2. registry://
+	rs := grpc.NewServer()
+	registrypb.RegisterRegistryServer(rs, registry.New())
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	go rs.Serve(lis)
+	defer rs.Stop()
+	regConn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer regConn.Close()
+	reg := registrypb.NewRegistryClient(regConn)
+	register := func(addr string) {
+		inst := &registrypb.Instance{Address: addr, Services: []string{"echo.Echo"}, Metadata: map[string]string{"zone": "a"}}
+		if _, err := reg.Register(context.Background(), &registrypb.RegisterRequest{Instance: inst}); err != nil {
+			log.Fatalf("failed to register %s: %v", addr, err)
+		}
+	}
+	register(addrs[0])
+	register(addrs[2])
+
+	cfg.Client.Address = discovery.RegistryTarget(lis.Addr().String(), "echo.Echo")
+	conn, err = bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+	c = pb.NewEchoClient(conn)
+	check(c, fmt.Sprintf("--- 2.1 %s ---", cfg.Client.Address), addrs[0], addrs[2])
+	if _, err := reg.Deregister(context.Background(), &registrypb.DeregisterRequest{Address: addrs[0]}); err != nil {
+		log.Fatalf("failed to deregister: %v", err)
+	}
+	check(c, fmt.Sprintf("--- 2.2 %s deregistered ---", addrs[0]), addrs[2])
+	register(addrs[1])
+	check(c, fmt.Sprintf("--- 2.3 %s registered ---", addrs[1]), addrs[1], addrs[2])
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("FAIL: %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
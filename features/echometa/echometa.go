@@ -0,0 +1,110 @@
+// Package echometa echoes request metadata back to clients as response headers
+// and trailers, and helps clients capture the headers and trailers of calls.
+//
+// The request metadata whose keys start with Prefix are echoed, including the
+// binary ones, whose keys end with "-bin". A "server-timing" trailer tells how
+// long the server took to handle the call, in the Server-Timing HTTP header
+// format, e.g. "handler;dur=12.345".
+package echometa
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Prefix is the prefix of the request metadata keys that are echoed.
+const Prefix = "x-echo-"
+
+// ServerTimingKey is the trailer key of the time taken by the server.
+const ServerTimingKey = "server-timing"
+
+// Echoed returns the request metadata of ctx that is echoed.
+func Echoed(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	echoed := metadata.MD{}
+	for k, v := range md {
+		if strings.HasPrefix(k, Prefix) {
+			echoed[k] = v
+		}
+	}
+	return echoed
+}
+
+// Trailer returns the echoed metadata of ctx with a server-timing entry for a
+// call started at start.
+func Trailer(ctx context.Context, start time.Time) metadata.MD {
+	dur := float64(time.Since(start)) / float64(time.Millisecond)
+	return metadata.Join(Echoed(ctx), metadata.Pairs(ServerTimingKey, "handler;dur="+strconv.FormatFloat(dur, 'f', 3, 64)))
+}
+
+// Capture holds the header and trailer of a call.
+type Capture struct {
+	Header  metadata.MD
+	Trailer metadata.MD
+}
+
+// CallOptions returns the call options that fill c when a unary call returns.
+func (c *Capture) CallOptions() []grpc.CallOption {
+	return []grpc.CallOption{grpc.Header(&c.Header), grpc.Trailer(&c.Trailer)}
+}
+
+// Stream fills c from a stream. The header is available once received, the
+// trailer once the stream is over, i.e. after Recv returned an error or io.EOF.
+func (c *Capture) Stream(s grpc.ClientStream) error {
+	header, err := s.Header()
+	if err != nil {
+		return err
+	}
+	c.Header = header
+	c.Trailer = s.Trailer()
+	return nil
+}
+
+// ServerTiming returns the time taken by the server according to the server-timing trailer.
+func (c *Capture) ServerTiming() (time.Duration, bool) {
+	for _, v := range c.Trailer.Get(ServerTimingKey) {
+		for _, param := range strings.Split(v, ";") {
+			if !strings.HasPrefix(param, "dur=") {
+				continue
+			}
+			ms, err := strconv.ParseFloat(strings.TrimPrefix(param, "dur="), 64)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	return 0, false
+}
+
+// Fprint prints the header and trailer of c to w, with the binary values in hex.
+func (c *Capture) Fprint(w io.Writer) {
+	for _, part := range []struct {
+		name string
+		md   metadata.MD
+	}{{"header", c.Header}, {"trailer", c.Trailer}} {
+		fmt.Fprintf(w, "%s:\n", part.name)
+		keys := make([]string, 0, len(part.md))
+		for k := range part.md {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range part.md[k] {
+				if strings.HasSuffix(k, "-bin") {
+					fmt.Fprintf(w, " - %s: %x\n", k, v)
+				} else {
+					fmt.Fprintf(w, " - %s: %s\n", k, v)
+				}
+			}
+		}
+	}
+}
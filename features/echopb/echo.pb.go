@@ -7,6 +7,7 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	_ "github.com/wangy8961/grpc-go-tutorial/validate"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -112,19 +113,21 @@ func init() {
 func init() { proto.RegisterFile("echo.proto", fileDescriptor_08134aea513e0001) }
 
 var fileDescriptor_08134aea513e0001 = []byte{
-	// 192 bytes of a gzipped FileDescriptorProto
+	// 216 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4a, 0x4d, 0xce, 0xc8,
-	0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x01, 0xb1, 0x95, 0xd4, 0xb9, 0xb8, 0x5d, 0x93,
-	0x33, 0xf2, 0x83, 0x52, 0x0b, 0x4b, 0x53, 0x8b, 0x4b, 0x84, 0x24, 0xb8, 0xd8, 0x73, 0x53, 0x8b,
-	0x8b, 0x13, 0xd3, 0x53, 0x25, 0x18, 0x15, 0x18, 0x35, 0x38, 0x83, 0x60, 0x5c, 0x25, 0x0d, 0x2e,
-	0x1e, 0x88, 0xc2, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0xdc, 0x2a, 0x8d, 0xba, 0x99, 0xb8, 0x58,
-	0x40, 0x4a, 0x85, 0x4c, 0xb8, 0x38, 0x43, 0xf3, 0x12, 0x8b, 0x2a, 0xc1, 0x1c, 0x41, 0x3d, 0xb0,
-	0xdd, 0x48, 0x96, 0x49, 0x09, 0x21, 0x0b, 0x41, 0x8c, 0x55, 0x62, 0x10, 0x72, 0xe0, 0x12, 0x0e,
-	0x4e, 0x2d, 0x2a, 0x4b, 0x2d, 0x0a, 0x2e, 0x29, 0x4a, 0x4d, 0xcc, 0xcd, 0xcc, 0x4b, 0x27, 0x49,
-	0xbf, 0x01, 0x23, 0xc8, 0x04, 0xe7, 0x9c, 0xcc, 0xd4, 0xbc, 0x12, 0xf2, 0x4c, 0xd0, 0x60, 0x14,
-	0xf2, 0xe4, 0x92, 0x72, 0xca, 0x4c, 0xc9, 0x2c, 0x4a, 0x4d, 0x2e, 0xc9, 0xcc, 0xcf, 0x4b, 0xcc,
-	0x21, 0xd7, 0x20, 0x03, 0x46, 0x27, 0x8e, 0x28, 0x36, 0x90, 0x54, 0x41, 0x52, 0x12, 0x1b, 0x38,
-	0xdc, 0x8d, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x15, 0x0f, 0xb3, 0x92, 0x85, 0x01, 0x00, 0x00,
+	0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x01, 0xb1, 0xa5, 0xc4, 0xcb, 0x12, 0x73, 0x32,
+	0x53, 0x12, 0x4b, 0x52, 0xf5, 0x61, 0x0c, 0x88, 0xb4, 0x92, 0x3e, 0x17, 0xb7, 0x6b, 0x72, 0x46,
+	0x7e, 0x50, 0x6a, 0x61, 0x69, 0x6a, 0x71, 0x89, 0x90, 0x02, 0x17, 0x7b, 0x6e, 0x6a, 0x71, 0x71,
+	0x62, 0x7a, 0xaa, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0xa7, 0x13, 0xdb, 0xa9, 0xcf, 0x12, 0x4c, 0x12,
+	0x5c, 0x41, 0x30, 0x61, 0x25, 0x0d, 0x2e, 0x1e, 0x88, 0x86, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54,
+	0x21, 0x09, 0x34, 0x1d, 0x70, 0x95, 0x46, 0xdd, 0x4c, 0x5c, 0x2c, 0x20, 0xa5, 0x42, 0x26, 0x5c,
+	0x9c, 0xa1, 0x79, 0x89, 0x45, 0x95, 0x60, 0x8e, 0xa0, 0x1e, 0xd8, 0x71, 0x48, 0x96, 0x4a, 0x09,
+	0x21, 0x0b, 0x41, 0x8c, 0x55, 0x62, 0x10, 0x72, 0xe0, 0x12, 0x0e, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d,
+	0x0a, 0x2e, 0x29, 0x4a, 0x4d, 0xcc, 0xcd, 0xcc, 0x4b, 0x27, 0x49, 0xbf, 0x01, 0x23, 0xc8, 0x04,
+	0xe7, 0x9c, 0xcc, 0xd4, 0xbc, 0x12, 0xf2, 0x4c, 0xd0, 0x60, 0x14, 0xf2, 0xe4, 0x92, 0x72, 0xca,
+	0x4c, 0xc9, 0x2c, 0x4a, 0x4d, 0x2e, 0xc9, 0xcc, 0xcf, 0x4b, 0xcc, 0x21, 0xd7, 0x20, 0x03, 0x46,
+	0x27, 0x8e, 0x28, 0x36, 0x90, 0x54, 0x41, 0x52, 0x12, 0x1b, 0x38, 0xe4, 0x8d, 0x01, 0x03, 0x00,
+	0x65, 0x20, 0x61, 0xfd, 0xa6, 0x01, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1,3 +1,3 @@
 #!/bin/bash
 
-protoc --go_out=plugins=grpc:. *.proto
\ No newline at end of file
+protoc -I. -I../.. --go_out=plugins=grpc:. *.proto
\ No newline at end of file
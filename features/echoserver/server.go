@@ -0,0 +1,122 @@
+// Package echoserver implements the Echo service shared by the examples under
+// features/. Each example registers it with its own interceptors and
+// credentials, so that every feature applies to the four kinds of RPC. The
+// examples that need a different behavior embed Server and override the
+// methods concerned.
+//
+// The request metadata with the echometa.Prefix is echoed back as response
+// headers and trailers, along with a server-timing trailer. The calls are not
+// printed: the example servers log them by enabling the logging interceptors of
+// bootstrap.InterceptorsConfig.
+package echoserver
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/features/echometa"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Server is used to implement echopb.EchoServer. The zero value is ready to use.
+type Server struct {
+	Repeat   int           // number of responses of ServerStreamingEcho, 3 if zero
+	Interval time.Duration // pause between the responses of ServerStreamingEcho
+	Count    bool          // ClientStreamingEcho answers with the number of messages instead of their concatenation
+}
+
+// UnaryEcho answers with the message of the request.
+func (s *Server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	// The header is sent with the response, the trailer after it
+	start := time.Now()
+	if err := grpc.SetHeader(ctx, echometa.Echoed(ctx)); err != nil {
+		return nil, err
+	}
+	defer func() { grpc.SetTrailer(ctx, echometa.Trailer(ctx, start)) }()
+
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// ServerStreamingEcho answers with the message of the request, Repeat times every Interval.
+func (s *Server) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
+	// The header is sent at once, so that the client gets it before the first response
+	start := time.Now()
+	if err := stream.SendHeader(echometa.Echoed(stream.Context())); err != nil {
+		return err
+	}
+	defer func() { stream.SetTrailer(echometa.Trailer(stream.Context(), start)) }()
+
+	n := s.Repeat
+	if n == 0 {
+		n = 3
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 && s.Interval > 0 {
+			select {
+			case <-stream.Context().Done():
+				// the client canceled the stream, or its deadline was exceeded
+				return status.FromContextError(stream.Context().Err()).Err()
+			case <-time.After(s.Interval):
+			}
+		}
+		if err := stream.Send(&pb.EchoResponse{Message: req.GetMessage()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientStreamingEcho answers with the messages of the requests joined by
+// spaces, or with their number if Count is set.
+func (s *Server) ClientStreamingEcho(stream pb.Echo_ClientStreamingEchoServer) error {
+	start := time.Now()
+	if err := stream.SetHeader(echometa.Echoed(stream.Context())); err != nil {
+		return err
+	}
+	defer func() { stream.SetTrailer(echometa.Trailer(stream.Context(), start)) }()
+
+	var msgs []string
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			resp := strings.Join(msgs, " ")
+			if s.Count {
+				resp = strconv.Itoa(len(msgs))
+			}
+			return stream.SendAndClose(&pb.EchoResponse{Message: resp})
+		}
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, in.GetMessage())
+	}
+}
+
+// BidirectionalStreamingEcho answers every request with its message.
+func (s *Server) BidirectionalStreamingEcho(stream pb.Echo_BidirectionalStreamingEchoServer) error {
+	// The header is sent at once, so that the client gets it before the first response
+	start := time.Now()
+	if err := stream.SendHeader(echometa.Echoed(stream.Context())); err != nil {
+		return err
+	}
+	defer func() { stream.SetTrailer(echometa.Trailer(stream.Context(), start)) }()
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(&pb.EchoResponse{Message: in.GetMessage()}); err != nil {
+			return err
+		}
+	}
+}
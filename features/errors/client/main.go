@@ -6,20 +6,30 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
-	"google.golang.org/grpc"
+	"github.com/wangy8961/grpc-go-tutorial/statusdetails"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
+	cfg := bootstrap.DefaultConfig()
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	lang := flag.String("lang", "en-US", "the preferred language of error messages, e.g. zh-CN")
+	times := flag.Int("n", 1, "the number of calls to make")
 	flag.Parse()
 
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, grpc.WithInsecure()) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	conn, err := bootstrap.Dial(&cfg.Client) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -29,21 +39,41 @@ func main() {
 
 	// Contact the server and print out its response.
 	msg := "Madman"
-	if len(os.Args) > 1 {
-		msg = os.Args[1]
+	if flag.NArg() > 0 {
+		msg = flag.Arg(0)
 	}
 
-	resp, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: msg}) // Now let’s look at how we call our service methods. Note that in gRPC-Go, RPCs operate in a blocking/synchronous mode, which means that the RPC call waits for the server to respond, and will either return a response or an error.
-	if err != nil {
-		// log.Fatalf("failed to call UnaryEcho: %v", err)
-		errStatus, _ := status.FromError(err)
-		fmt.Printf("Error Code: %v\n", errStatus.Code())
-		fmt.Printf("Error Description: %v\n\n", errStatus.Message())
+	// The server returns a LocalizedMessage in the language we ask for
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", *lang)
+
+	for i := 0; i < *times; i++ {
+		resp, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: msg}) // Now let’s look at how we call our service methods. Note that in gRPC-Go, RPCs operate in a blocking/synchronous mode, which means that the RPC call waits for the server to respond, and will either return a response or an error.
+		if err != nil {
+			// log.Fatalf("failed to call UnaryEcho: %v", err)
+			// Print the code, the message and every detail attached by the server
+			fmt.Print(statusdetails.Sprint(err))
+			fmt.Println()
 
-		if codes.InvalidArgument == errStatus.Code() {
-			fmt.Println("You can take specific action based on specific error!")
+			// Take specific action based on machine-readable details, not on the message string
+			switch status.Code(err) {
+			case codes.InvalidArgument:
+				var br errdetails.BadRequest
+				if statusdetails.Find(err, &br) {
+					for _, v := range br.GetFieldViolations() {
+						fmt.Printf("You should fix the field %q of your request!\n", v.GetField())
+					}
+				}
+			case codes.ResourceExhausted:
+				if delay, ok := statusdetails.RetryDelay(err); ok {
+					fmt.Printf("You should not retry before %v!\n", delay)
+				}
+			}
+			if reason, domain, ok := statusdetails.Reason(err); ok {
+				fmt.Printf("Failure reason: %s (%s)\n", reason, domain)
+			}
+			continue
 		}
+		fmt.Printf("response:\n")
+		fmt.Printf(" - %q\n", resp.GetMessage())
 	}
-	fmt.Printf("response:\n")
-	fmt.Printf(" - %q\n", resp.GetMessage())
 }
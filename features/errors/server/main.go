@@ -7,56 +7,231 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/statusdetails"
+	"github.com/wangy8961/grpc-go-tutorial/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
+// errorDomain is the ErrorInfo domain of every error returned by this server
+const errorDomain = "echo.madmalls.com"
+
+// ErrorInfo reasons returned by this server
+const (
+	reasonFieldViolation    = "FIELD_VIOLATION"
+	reasonRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
+)
+
+// localizedMessages holds the LocalizedMessage templates of each reason, by locale.
+// The first locale is used when the client does not ask for a supported one.
+var localizedMessages = map[string][][2]string{
+	reasonFieldViolation: {
+		{"en-US", "Some fields of your request are invalid, please correct them and try again."},
+		{"zh-CN", "请求中的部分字段不合法，请修改后重试。"},
+	},
+	reasonRateLimitExceeded: {
+		{"en-US", "Too many requests, please try again in %v."},
+		{"zh-CN", "请求过于频繁，请在 %v 后重试。"},
+	},
+}
+
 // server is used to implement echopb.EchoServer.
-type server struct{}
+// Only UnaryEcho is rate limited, the other RPCs are the ones of echoserver.Server.
+type server struct {
+	echoserver.Server
 
-/*
-func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UnaryEcho not implemented")
+	debug bool // attach a DebugInfo to every error
+
+	limit  int           // maximum number of UnaryEcho calls per client within window
+	window time.Duration // length of the rate limiting window
+
+	mu    sync.Mutex
+	calls map[string][]time.Time // client -> times of its calls within the current window
 }
-*/
+
 func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
 	fmt.Printf("--- gRPC Unary RPC ---\n")
 	fmt.Printf("request received: %v\n", req)
 
-	if len(req.GetMessage()) > 10 {
-		return nil, status.Errorf(codes.InvalidArgument, "Length of `Message` cannot be more than 10 characters")
+	// The length of `Message` is checked by the validation interceptor, see the (validate.rules) option in echo.proto
+	client := clientOf(ctx)
+	if wait := s.allow(client); wait > 0 {
+		return nil, s.newStatus(ctx, codes.ResourceExhausted, reasonRateLimitExceeded,
+			fmt.Sprintf("client %s exceeded %d calls per %v", client, s.limit, s.window),
+			[]interface{}{wait.Round(time.Second)},
+			map[string]string{
+				"limit":  strconv.Itoa(s.limit),
+				"window": s.window.String(),
+			},
+			&errdetails.QuotaFailure{
+				Violations: []*errdetails.QuotaFailure_Violation{{
+					Subject:     "client:" + client,
+					Description: fmt.Sprintf("Limit of %d UnaryEcho calls per %v exceeded", s.limit, s.window),
+				}},
+			},
+			&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(wait)},
+		).Err()
 	}
 	return &pb.EchoResponse{Message: req.GetMessage()}, nil
 }
 
-func (s *server) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ServerStreamingEcho not implemented")
+// allow records a call of client and returns how long it has to wait before
+// calling again, or 0 if the call is allowed.
+func (s *server) allow(client string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var recent []time.Time
+	for _, t := range s.calls[client] {
+		if now.Sub(t) < s.window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= s.limit {
+		s.calls[client] = recent
+		return recent[0].Add(s.window).Sub(now)
+	}
+	s.calls[client] = append(recent, now)
+	return 0
+}
+
+// clientOf identifies the caller by its IP address
+func clientOf(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// newStatus builds a status with an ErrorInfo, a LocalizedMessage in the language
+// asked for by the client's "accept-language" header, the given extra details
+// and, in debug mode, a DebugInfo with the current stack.
+func (s *server) newStatus(ctx context.Context, code codes.Code, reason, msg string, localizedArgs []interface{}, meta map[string]string, extra ...proto.Message) *status.Status {
+	details := append([]proto.Message{}, extra...)
+	details = append(details, &statusdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: meta,
+	})
+	locale, text := localize(ctx, reason)
+	details = append(details, &errdetails.LocalizedMessage{
+		Locale:  locale,
+		Message: fmt.Sprintf(text, localizedArgs...),
+	})
+	if s.debug {
+		details = append(details, &errdetails.DebugInfo{
+			StackEntries: strings.Split(strings.TrimSpace(string(debug.Stack())), "\n"),
+			Detail:       msg,
+		})
+	}
+
+	st := status.New(code, msg)
+	ds, err := st.WithDetails(details...)
+	if err != nil {
+		log.Printf("failed to attach error details: %v", err)
+		return st
+	}
+	return ds
 }
 
-func (s *server) ClientStreamingEcho(stream pb.Echo_ClientStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ClientStreamingEcho not implemented")
+// localize picks the LocalizedMessage template of reason for the first supported
+// locale listed in the "accept-language" header, e.g. "zh-CN,en-US;q=0.8".
+func localize(ctx context.Context, reason string) (locale, text string) {
+	templates := localizedMessages[reason]
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, header := range md.Get("accept-language") {
+			for _, lang := range strings.Split(header, ",") {
+				lang = strings.TrimSpace(strings.SplitN(lang, ";", 2)[0])
+				for _, t := range templates {
+					if strings.EqualFold(t[0], lang) {
+						return t[0], t[1]
+					}
+				}
+			}
+		}
+	}
+	return templates[0][0], templates[0][1]
 }
 
-func (s *server) BidirectionalStreamingEcho(stream pb.Echo_BidirectionalStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method BidirectionalStreamingEcho not implemented")
+// server-side unary interceptor (For Error Details)
+// It adds an ErrorInfo, a LocalizedMessage and, in debug mode, a DebugInfo to the
+// InvalidArgument errors of the validation interceptor, which only carry a BadRequest.
+func (s *server) unaryDetailsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	m, err := handler(ctx, req)
+	if err == nil {
+		return m, nil
+	}
+
+	st := status.Convert(err)
+	var br errdetails.BadRequest
+	if st.Code() != codes.InvalidArgument || !statusdetails.Find(err, &br) {
+		return m, err
+	}
+	var fields []string
+	for _, v := range br.GetFieldViolations() {
+		fields = append(fields, v.GetField())
+	}
+	return m, s.newStatus(ctx, codes.InvalidArgument, reasonFieldViolation, st.Message(), nil,
+		map[string]string{"fields": strings.Join(fields, ",")},
+		&br,
+	).Err()
 }
 
 func main() {
-	port := flag.Int("port", 50051, "the port to serve on")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Interceptors.Logging = true // Log one JSON line per call
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	debugMode := flag.Bool("debug", false, "attach DebugInfo (stack traces) to errors, do not enable in production")
+	limit := flag.Int("limit", 5, "maximum number of UnaryEcho calls per client per minute")
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port)) // Specify the port we want to use to listen for client requests
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
-	fmt.Printf("server listening at %v\n", lis.Addr())
 
-	s := grpc.NewServer()                // Create an instance of the gRPC server
-	pb.RegisterEchoServer(s, &server{})  // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil { // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	srv := &server{
+		debug:  *debugMode,
+		limit:  *limit,
+		window: time.Minute,
+		calls:  make(map[string][]time.Time),
+	}
+
+	s, err := bootstrap.NewServer(cfg,
+		bootstrap.WithUnaryInterceptors(
+			// Enrich the errors of the validation interceptor with more details
+			srv.unaryDetailsInterceptor,
+			// Reject requests that violate the (validate.rules) declared in echo.proto
+			validate.UnaryServerInterceptor(),
+		),
+		bootstrap.WithStreamInterceptors(validate.StreamServerInterceptor()),
+	) // Create an instance of the gRPC server
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+	pb.RegisterEchoServer(s.Server, srv) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {    // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
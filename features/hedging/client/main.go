@@ -0,0 +1,107 @@
+// Package main implements a client for Echo service that measures the latency of hedged calls.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/hedging"
+	"google.golang.org/grpc"
+)
+
+// attempts counts the requests actually sent to the server.
+var attempts int64
+
+func countingInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	atomic.AddInt64(&attempts, 1)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// measure makes n sequential calls and prints their latency percentiles.
+func measure(name string, c pb.EchoClient, n int) {
+	atomic.StoreInt64(&attempts, 0)
+	latencies := make([]time.Duration, 0, n)
+	failures := 0
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		start := time.Now()
+		_, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: fmt.Sprintf("%d", i)})
+		cancel()
+		if err != nil {
+			failures++
+			continue
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	if len(latencies) == 0 {
+		fmt.Printf("%-8s all %d calls failed\n", name, n)
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	p := func(q float64) time.Duration {
+		return latencies[int(q*float64(len(latencies)-1))].Round(time.Millisecond / 10)
+	}
+	fmt.Printf("%-8s p50=%-8v p95=%-8v p99=%-8v max=%-8v requests=%d failures=%d\n",
+		name, p(0.5), p(0.95), p(0.99), p(1), atomic.LoadInt64(&attempts), failures)
+}
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	n := flag.Int("n", 200, "the number of calls to make")
+	delay := flag.Duration("delay", 50*time.Millisecond, "the hedging delay")
+	percentile := flag.Float64("percentile", 0, "hedge after the observed percentile of latency instead of -delay, e.g. 0.95")
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	hedgingOpts := []hedging.Option{
+		hedging.WithMethods("/echo.Echo/UnaryEcho"),
+		hedging.WithMaxAttempts(3),
+		hedging.WithDelay(*delay),
+		hedging.WithBudget(hedging.NewBudget(0.1, 10)), // at most 10% more requests
+	}
+	if *percentile > 0 {
+		hedgingOpts = append(hedgingOpts, hedging.WithPercentile(*percentile))
+	}
+
+	// Set up a connection to the server.
+	conn, err := bootstrap.Dial(&cfg.Client,
+		grpc.WithUnaryInterceptor(countingInterceptor),
+	)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+
+	// The same server, but every call is hedged before its attempts are counted.
+	// The attempts run concurrently, so the counting interceptor is called from
+	// the invoker instead of being chained with grpc_middleware.ChainUnaryClient,
+	// whose chains are not safe for concurrent invocations.
+	hedge := hedging.UnaryClientInterceptor(hedgingOpts...)
+	hedgedConn, err := bootstrap.Dial(&cfg.Client,
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return hedge(ctx, method, req, reply, cc, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return countingInterceptor(ctx, method, req, reply, cc, invoker, opts...)
+			}, opts...)
+		}),
+	)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer hedgedConn.Close()
+
+	measure("plain", pb.NewEchoClient(conn), *n)
+	measure("hedged", pb.NewEchoClient(hedgedConn), *n)
+}
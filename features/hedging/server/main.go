@@ -0,0 +1,86 @@
+// Package main implements a server for Echo service with an injected tail latency.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/hedging"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// server is used to implement echopb.EchoServer.
+// Most calls take latency, but a tailRate fraction of them take tailLatency.
+type server struct {
+	echoserver.Server
+
+	latency     time.Duration
+	tailRate    float64
+	tailLatency time.Duration
+
+	calls, hedged, canceled int64
+}
+
+func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	atomic.AddInt64(&s.calls, 1)
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(hedging.AttemptKey)) > 0 {
+		atomic.AddInt64(&s.hedged, 1)
+	}
+
+	d := s.latency
+	if rand.Float64() < s.tailRate {
+		d = s.tailLatency
+	}
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+		// The client canceled the attempt, because another one answered first
+		atomic.AddInt64(&s.canceled, 1)
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// report prints the number of calls served every interval.
+func (s *server) report(interval time.Duration) {
+	for range time.Tick(interval) {
+		fmt.Printf("calls: %d, hedged attempts: %d, canceled: %d\n",
+			atomic.LoadInt64(&s.calls), atomic.LoadInt64(&s.hedged), atomic.LoadInt64(&s.canceled))
+	}
+}
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Interceptors.Logging = true // Log one JSON line per call
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	latency := flag.Duration("latency", 10*time.Millisecond, "the latency of most calls")
+	tailRate := flag.Float64("tail-rate", 0.05, "the fraction of calls that are slow")
+	tailLatency := flag.Duration("tail-latency", 500*time.Millisecond, "the latency of the slow calls")
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	srv := &server{latency: *latency, tailRate: *tailRate, tailLatency: *tailLatency}
+	go srv.report(5 * time.Second)
+
+	s, err := bootstrap.NewServer(cfg) // Create an instance of the gRPC server
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+	pb.RegisterEchoServer(s.Server, srv) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {    // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
@@ -7,27 +7,25 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"time"
+	"os"
 
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc/credentials/oauth"
 
-	"google.golang.org/grpc/credentials"
-
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/logging"
+	"github.com/wangy8961/grpc-go-tutorial/metrics"
 	"google.golang.org/grpc"
 )
 
 func unaryCall(client pb.EchoClient) {
 	fmt.Printf("--- gRPC Unary RPC Call ---\n")
 
-	// 设置 10 秒超时时长，可参考 https://madmalls.com/blog/post/grpc-deadline/#21-contextwithtimeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
+	// 超时时长由 service config 设置，见 service_config.json
 	// 调用 Unary RPC
 	req := &pb.EchoRequest{Message: "madmalls.com"}
-	resp, err := client.UnaryEcho(ctx, req)
+	resp, err := client.UnaryEcho(context.Background(), req)
 	if err != nil {
 		log.Fatalf("failed to call UnaryEcho: %v", err)
 	}
@@ -39,12 +37,8 @@ func unaryCall(client pb.EchoClient) {
 func bidirectionalStreamingCall(c pb.EchoClient) {
 	fmt.Printf("--- gRPC Bidirectional Streaming RPC Call ---\n")
 
-	// 设置 10 秒超时时长，可参考 https://madmalls.com/blog/post/grpc-deadline/#21-contextwithtimeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Make bidirectional streaming RPC
-	stream, err := c.BidirectionalStreamingEcho(ctx)
+	// Make bidirectional streaming RPC, with the 10s timeout of service_config.json
+	stream, err := c.BidirectionalStreamingEcho(context.Background())
 	if err != nil {
 		log.Fatalf("failed to call BidirectionalStreamingEcho: %v", err)
 	}
@@ -84,18 +78,6 @@ func unaryAuthInterceptor(ctx context.Context, method string, req, reply interfa
 	return err
 }
 
-// client-side unary interceptor (For Logging)
-func unaryLogInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-	// Logic before invoking the invoker
-	start := time.Now()
-	// Calls the invoker to execute RPC
-	err := invoker(ctx, method, req, reply, cc, opts...)
-	// Logic after invoking the invoker
-	log.Printf("Invoked RPC method: %s, Duration time: %s, Error: %v", method, time.Since(start), err)
-
-	return err
-}
-
 // client-side streaming interceptor (For Authentication)
 func streamAuthInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
 	opts = append(opts, grpc.PerRPCCredentials(oauth.NewOauthAccess(&oauth2.Token{
@@ -109,29 +91,35 @@ func streamAuthInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.
 }
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
-	certFile := flag.String("cacert", "cacert.pem", "CA root certificate")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.TLS.CAFile = "cacert.pem"
+	cfg.Client.ServiceConfigFile = bootstrap.SourcePath("service_config.json") // 各方法的超时时长
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	creds, err := credentials.NewClientTLSFromFile(*certFile, "")
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to load CA root certificate: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
 
+	m := metrics.NewClient()
 	opts := []grpc.DialOption{
-		// 1. TLS Credential
-		grpc.WithTransportCredentials(creds),
-		// 2. Client Unary Interceptors
+		// Client Unary Interceptors
 		grpc.WithChainUnaryInterceptor(
 			unaryAuthInterceptor,
-			unaryLogInterceptor,
+			logging.UnaryClientInterceptor(),
+			m.UnaryClientInterceptor(),
+		),
+		// 3. Client Streaming Interceptors
+		grpc.WithChainStreamInterceptor(
+			streamAuthInterceptor,
+			logging.StreamClientInterceptor(),
+			m.StreamClientInterceptor(),
 		),
-		// 3. Client Streaming Interceptor
-		grpc.WithStreamInterceptor(streamAuthInterceptor),
 	}
 
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, opts...) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	conn, err := bootstrap.Dial(&cfg.Client, opts...) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -144,5 +132,8 @@ func main() {
 	unaryCall(c)
 
 	// 2. Bidirectional Streaming RPC Call
-	// bidirectionalStreamingCall(c)
+	bidirectionalStreamingCall(c)
+
+	fmt.Printf("--- Metrics ---\n")
+	m.Fprint(os.Stdout)
 }
@@ -5,63 +5,27 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net"
 	"strings"
-	"time"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	"github.com/wangy8961/grpc-go-tutorial/logging"
+	"github.com/wangy8961/grpc-go-tutorial/recovery"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
-
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 )
 
-// server is used to implement echopb.EchoServer.
-type server struct{}
-
-func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
-	fmt.Printf("--- gRPC Unary RPC ---\n")
-	fmt.Printf("request received: %v\n", req)
-
-	return &pb.EchoResponse{Message: req.GetMessage()}, nil
-}
-
-func (s *server) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ServerStreamingEcho not implemented")
-}
-
-func (s *server) ClientStreamingEcho(stream pb.Echo_ClientStreamingEchoServer) error {
-	return status.Errorf(codes.Unimplemented, "method ClientStreamingEcho not implemented")
-}
-
-func (s *server) BidirectionalStreamingEcho(stream pb.Echo_BidirectionalStreamingEchoServer) error {
-	fmt.Printf("--- gRPC Bidirectional Streaming RPC ---\n")
-
-	for {
-		in, err := stream.Recv()
-		if err != nil {
-			if err == io.EOF {
-				fmt.Printf("Receiving client streaming data completed\n")
-				return nil
-			}
-			fmt.Printf("Error while receiving client streaming data: %v", err)
-			return err
-		}
-		fmt.Printf("request received: %q\n", in.Message)
-
-		if err := stream.Send(&pb.EchoResponse{Message: in.Message}); err != nil {
-			fmt.Printf("Error while sending streaming data to client: %v", err)
-		}
-	}
-}
-
 // server-side unary interceptor (For Authentication)
 func unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	// The health probes have no credentials
+	if healthcheck.IsHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
 	// md 的值类似于: map[:authority:[192.168.40.123:50051] authorization:[Bearer some-secret-token] content-type:[application/grpc] user-agent:[grpc-go/1.20.1]]
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -97,23 +61,12 @@ func unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.Unary
 	return m, err
 }
 
-// server-side unary interceptor (For Logging)
-func unaryLogInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	// Logic before invoking the invoker
-	start := time.Now()
-	// Calls the handler to execute RPC
-	m, err := handler(ctx, req)
-	if err != nil {
-		log.Printf("failed to handler Unary RPC: %v\n", err)
-	}
-	// Logic after invoking the invoker
-	log.Printf("Handler RPC method: %s, Duration time: %s, Error: %v", info.FullMethod, time.Since(start), err)
-
-	return m, err
-}
-
 // server-side streaming interceptor (For Authentication)
 func streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	// The health probes have no credentials
+	if healthcheck.IsHealthMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
 	// md 的值类似于: map[:authority:[192.168.40.123:50051] authorization:[Bearer some-secret-token] content-type:[application/grpc] user-agent:[grpc-go/1.20.1]]
 	md, ok := metadata.FromIncomingContext(ss.Context())
 	if !ok {
@@ -148,39 +101,37 @@ func streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.Str
 	return err
 }
 
+// reportPanic is the recovery hook, which would report the panics to an error tracker
+func reportPanic(ctx context.Context, method string, p interface{}, stack []byte) {
+	fmt.Printf("reporting panic of %s (request %s): %v\n", method, logging.RequestID(ctx), p)
+}
+
 func main() {
-	port := flag.Int("port", 50051, "the port to serve on")
-	certFile := flag.String("certfile", "server.crt", "Server certificate")
-	keyFile := flag.String("keyfile", "server.key", "Server private key")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.TLS.CertFile = "server.crt"
+	cfg.Server.TLS.KeyFile = "server.key"
+	cfg.Admin.Address = ":9090"
+	cfg.Server.Interceptors = bootstrap.InterceptorsConfig{Logging: true, Metrics: true, Recovery: true}
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port)) // Specify the port we want to use to listen for client requests
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
-	fmt.Printf("server listening at %v\n", lis.Addr())
 
-	creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
+	s, err := bootstrap.NewServer(cfg,
+		// The logging, metrics and recovery interceptors of the config run before the authentication
+		bootstrap.WithRecovery(recovery.WithHook(reportPanic)),
+		bootstrap.WithUnaryInterceptors(unaryAuthInterceptor),
+		bootstrap.WithStreamInterceptors(streamAuthInterceptor),
+	) // Create an instance of the gRPC server
 	if err != nil {
-		log.Fatalf("failed to load certificates: %v", err)
+		log.Fatalf("failed to create server: %v", err)
 	}
 
-	opts := []grpc.ServerOption{
-		// 1. TLS Credential
-		grpc.Creds(creds),
-		// 2. Server Unary Interceptors
-		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-			unaryAuthInterceptor,
-			unaryLogInterceptor,
-		)),
-		// 3. Server Streaming Interceptor
-		grpc.StreamInterceptor(streamAuthInterceptor),
-	}
-
-	s := grpc.NewServer(opts...) // Create an instance of the gRPC server
-
-	pb.RegisterEchoServer(s, &server{})  // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil { // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	pb.RegisterEchoServer(s.Server, &echoserver.Server{}) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {                     // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
@@ -0,0 +1,279 @@
+// Package main balances the calls of a Math client across three math_server
+// instances with round_robin, and checks where the calls land:
+//
+//  1. all the instances get calls;
+//  2. an instance reporting NOT_SERVING through its health service, set on
+//     its admin port, gets no more calls, although it is still running;
+//  3. a killed instance gets no more calls either, the others get them all;
+//  4. the instance reporting SERVING again gets calls again.
+//
+// The client dials the comma-separated list of the addresses of the
+// instances, as math_client -addr localhost:50061,localhost:50062 would.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	pb "github.com/wangy8961/grpc-go-tutorial/math/mathpb"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// instance is a math_server process.
+type instance struct {
+	addr, admin string
+	cmd         *exec.Cmd
+}
+
+// start starts the math_server binary on port, with its admin endpoints on
+// adminPort, writing its output to the log file.
+func start(binary string, port, adminPort int, logFile *os.File) (*instance, error) {
+	in := &instance{
+		addr:  fmt.Sprintf("localhost:%d", port),
+		admin: fmt.Sprintf("localhost:%d", adminPort),
+	}
+	in.cmd = exec.Command(binary,
+		"-port", fmt.Sprint(port),
+		"-admin-port", fmt.Sprint(adminPort),
+		"-shutdown-grace", "1s",
+	)
+	in.cmd.Stdout = logFile
+	in.cmd.Stderr = logFile
+	if err := in.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// waitServing waits until the health service of the instance reports SERVING.
+func (in *instance) waitServing(timeout time.Duration) error {
+	cfg := bootstrap.DefaultConfig().Client
+	cfg.Address = in.addr
+	conn, err := bootstrap.Dial(&cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		s, err := healthcheck.Check(ctx, conn, "")
+		cancel()
+		if err == nil && s == healthpb.HealthCheckResponse_SERVING {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not serving after %v: %v %v", in.addr, timeout, s, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// setServing sets the serving status of the instance through its admin port.
+func (in *instance) setServing(status healthpb.HealthCheckResponse_ServingStatus) error {
+	req, err := http.NewRequest(http.MethodPut, "http://"+in.admin+"/health?status="+status.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// stop stops the instance gracefully, or kills it.
+func (in *instance) stop(kill bool) {
+	if in.cmd.ProcessState != nil {
+		return
+	}
+	if kill {
+		in.cmd.Process.Kill()
+	} else {
+		in.cmd.Process.Signal(syscall.SIGTERM)
+	}
+	in.cmd.Wait()
+}
+
+// spread makes n Sum calls and returns the number of calls served by each
+// address, by port as the peers are resolved, and the number of failed calls.
+func spread(c pb.MathClient, n int) (map[string]int, int) {
+	served := make(map[string]int)
+	failed := 0
+	for i := 0; i < n; i++ {
+		var p peer.Peer
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := c.Sum(ctx, &pb.SumRequest{FirstNum: int32(i), SecondNum: 1}, grpc.Peer(&p))
+		cancel()
+		if err != nil {
+			failed++
+			continue
+		}
+		_, port, _ := net.SplitHostPort(p.Addr.String())
+		served[port]++
+	}
+	return served, failed
+}
+
+// waitSpread makes rounds of n calls until the calls land on the ports only,
+// within timeout, and returns the last round with the failures of all rounds.
+func waitSpread(c pb.MathClient, n int, timeout time.Duration, ports ...string) (map[string]int, int, bool) {
+	deadline := time.Now().Add(timeout)
+	failed := 0
+	for {
+		served, f := spread(c, n)
+		failed += f
+		if f == 0 && onlyOn(served, ports) {
+			return served, failed, true
+		}
+		if time.Now().After(deadline) {
+			return served, failed, false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// onlyOn reports whether all the ports, and only them, served calls.
+func onlyOn(served map[string]int, ports []string) bool {
+	if len(served) != len(ports) {
+		return false
+	}
+	for _, p := range ports {
+		if served[p] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func format(served map[string]int) string {
+	var ports []string
+	for p := range served {
+		ports = append(ports, p)
+	}
+	sort.Strings(ports)
+	var s []string
+	for _, p := range ports {
+		s = append(s, fmt.Sprintf(":%s %d", p, served[p]))
+	}
+	return strings.Join(s, ", ")
+}
+
+func main() {
+	binary := flag.String("server", "", "the math_server binary, built from math/math_server if empty")
+	basePort := flag.Int("port", 50061, "the port of the first instance, the others listen on the next ones")
+	baseAdminPort := flag.Int("admin-port", 9161, "the admin port of the first instance, the others listen on the next ones")
+	calls := flag.Int("calls", 30, "the number of calls of each round")
+	flag.Parse()
+
+	dir, err := ioutil.TempDir("", "loadbalancing")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	// Stop the instances before exiting, main does not return on failures
+	var instances []*instance
+	cleanup := func() {
+		for _, in := range instances {
+			in.stop(false)
+		}
+		os.RemoveAll(dir)
+	}
+	fatalf := func(format string, v ...interface{}) {
+		cleanup()
+		log.Fatalf(format, v...)
+	}
+	if *binary == "" {
+		*binary = filepath.Join(dir, "math_server")
+		build := exec.Command("go", "build", "-o", *binary, "github.com/wangy8961/grpc-go-tutorial/math/math_server")
+		build.Stderr = os.Stderr
+		if err := build.Run(); err != nil {
+			fatalf("failed to build math_server: %v", err)
+		}
+	}
+	logFile, err := os.Create(filepath.Join(dir, "math_server.log"))
+	if err != nil {
+		fatalf("failed to create log file: %v", err)
+	}
+	defer logFile.Close()
+
+	// Start three instances
+	var addrs, ports []string
+	for i := 0; i < 3; i++ {
+		in, err := start(*binary, *basePort+i, *baseAdminPort+i, logFile)
+		if err != nil {
+			fatalf("failed to start math_server: %v", err)
+		}
+		instances = append(instances, in)
+		addrs = append(addrs, in.addr)
+		ports = append(ports, fmt.Sprint(*basePort+i))
+	}
+	for _, in := range instances {
+		if err := in.waitServing(10 * time.Second); err != nil {
+			fatalf("failed to start math_server: %v", err)
+		}
+	}
+
+	// round_robin and the health checks are the defaults of the client config
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.Address = strings.Join(addrs, ",")
+	conn, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		fatalf("did not connect: %v", err)
+	}
+	c := pb.NewMathClient(conn)
+
+	var problems []string
+	check := func(title string, ports ...string) {
+		served, failed, ok := waitSpread(c, *calls, 5*time.Second, ports...)
+		fmt.Printf("%s\n  served: %s, failed: %d\n", title, format(served), failed)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: the calls did not land on :%s only", title, strings.Join(ports, ", :")))
+		}
+	}
+
+	check("--- 1. Three healthy instances ---", ports...)
+
+	if err := instances[1].setServing(healthpb.HealthCheckResponse_NOT_SERVING); err != nil {
+		fatalf("failed to set the serving status: %v", err)
+	}
+	check(fmt.Sprintf("--- 2. %s reports NOT_SERVING ---", addrs[1]), ports[0], ports[2])
+
+	instances[2].stop(true)
+	check(fmt.Sprintf("--- 3. %s is killed ---", addrs[2]), ports[0])
+
+	if err := instances[1].setServing(healthpb.HealthCheckResponse_SERVING); err != nil {
+		fatalf("failed to set the serving status: %v", err)
+	}
+	check(fmt.Sprintf("--- 4. %s reports SERVING again ---", addrs[1]), ports[0], ports[1])
+
+	conn.Close()
+	cleanup()
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("FAIL: %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
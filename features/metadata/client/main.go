@@ -0,0 +1,118 @@
+// Package main implements a client for Echo service that reads response headers and trailers.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/features/echometa"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"google.golang.org/grpc/metadata"
+)
+
+// newContext returns a context whose metadata is echoed by the server, including a binary value.
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		"x-echo-request-id", fmt.Sprintf("%d", time.Now().UnixNano()),
+		"x-echo-client", "metadata-example",
+		// Binary values are base64-encoded on the wire by gRPC
+		"x-echo-token-bin", string([]byte{0xde, 0xad, 0xbe, 0xef}),
+		// Not echoed, it lacks the prefix
+		"secret", "not-echoed",
+	)
+	return ctx, cancel
+}
+
+func unaryCall(c pb.EchoClient) {
+	fmt.Printf("--- gRPC Unary RPC Call ---\n")
+	ctx, cancel := newContext()
+	defer cancel()
+
+	var capture echometa.Capture
+	resp, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "madmalls.com"}, capture.CallOptions()...)
+	if err != nil {
+		fmt.Printf("failed to call UnaryEcho: %v\n", err)
+		return
+	}
+	fmt.Printf("response:\n")
+	fmt.Printf(" - %q\n", resp.GetMessage())
+	capture.Fprint(os.Stdout)
+	if d, ok := capture.ServerTiming(); ok {
+		fmt.Printf("server timing: %v\n", d)
+	}
+}
+
+func serverSideStreamingCall(c pb.EchoClient) {
+	fmt.Printf("--- gRPC Server-side Streaming RPC Call ---\n")
+	ctx, cancel := newContext()
+	defer cancel()
+
+	stream, err := c.ServerStreamingEcho(ctx, &pb.EchoRequest{Message: "madmalls.com"})
+	if err != nil {
+		fmt.Printf("failed to call ServerStreamingEcho: %v\n", err)
+		return
+	}
+
+	// The server sends the header before the first response, so it is available at once
+	header, err := stream.Header()
+	if err != nil {
+		fmt.Printf("failed to receive header: %v\n", err)
+		return
+	}
+	fmt.Printf("header received before the first response: x-echo-request-id=%v\n", header.Get("x-echo-request-id"))
+
+	fmt.Printf("response:\n")
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("failed to receive: %v\n", err)
+			return
+		}
+		fmt.Printf(" - %q\n", resp.GetMessage())
+	}
+
+	// The trailer is only available once the stream is over
+	var capture echometa.Capture
+	if err := capture.Stream(stream); err != nil {
+		fmt.Printf("failed to capture metadata: %v\n", err)
+		return
+	}
+	capture.Fprint(os.Stdout)
+	if d, ok := capture.ServerTiming(); ok {
+		fmt.Printf("server timing: %v\n", d)
+	}
+}
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	// Set up a connection to the server.
+	conn, err := bootstrap.Dial(&cfg.Client) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+
+	c := pb.NewEchoClient(conn) // Once the gRPC channel is setup, we need a client stub to perform RPCs. We get this using the NewEchoClient method provided in the pb package we generated from our .proto.
+
+	unaryCall(c)
+	fmt.Println()
+	serverSideStreamingCall(c)
+}
@@ -0,0 +1,34 @@
+// Package main implements a server for Echo service that echoes request metadata.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+)
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Interceptors.Logging = true // Log one JSON line per call
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	s, err := bootstrap.NewServer(cfg) // Create an instance of the gRPC server
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+	// The request metadata with the "x-echo-" prefix is sent back as headers and trailers
+	pb.RegisterEchoServer(s.Server, &echoserver.Server{Interval: 500 * time.Millisecond}) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {                                                     // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
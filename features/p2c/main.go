@@ -0,0 +1,283 @@
+// Package main compares the p2c load balancing policy with round_robin, on
+// three Math servers running in the same process, one of them misbehaving:
+//
+//  1. skewed load: the first server is busy with huge PrimeFactors requests,
+//     so the Sum calls it gets wait for a worker, and take much longer than
+//     on the others. round_robin keeps sending it a third of the calls, p2c
+//     sends it fewer and fewer as its latency and outstanding calls grow:
+//     the p99 latency drops;
+//  2. failing backend: the third server answers UNAVAILABLE to every Sum call
+//     while its health check still reports SERVING. round_robin keeps sending
+//     it a third of the calls, p2c ejects it after a few failures.
+//
+// Each policy makes the same number of Sum calls from the same number of
+// concurrent callers, and the latencies, errors and share of the calls of
+// each server are printed.
+//
+// The servers share the CPUs of the process, so they simulate the work of the
+// calls by holding one of their workers for a while, rather than burning CPU.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	pb "github.com/wangy8961/grpc-go-tutorial/math/mathpb"
+	"github.com/wangy8961/grpc-go-tutorial/p2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	workers  = flag.Int("workers", 4, "the number of calls each server handles at the same time")
+	sumWork  = flag.Duration("sum-work", time.Millisecond, "how long a Sum call holds a worker")
+	hugeWork = flag.Duration("huge-work", 100*time.Millisecond, "how long a huge PrimeFactors call holds a worker")
+)
+
+// server is a Math server with a fixed number of workers, the calls waiting
+// for one when they are all busy.
+type server struct {
+	pb.UnimplementedMathServer
+	workers chan struct{}
+	failing int32 // answer UNAVAILABLE to Sum when 1
+}
+
+func (s *server) work(ctx context.Context, d time.Duration) error {
+	select {
+	case s.workers <- struct{}{}:
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	time.Sleep(d)
+	<-s.workers
+	return nil
+}
+
+func (s *server) Sum(ctx context.Context, in *pb.SumRequest) (*pb.SumResponse, error) {
+	if atomic.LoadInt32(&s.failing) == 1 {
+		return nil, status.Errorf(codes.Unavailable, "out of order")
+	}
+	if err := s.work(ctx, *sumWork); err != nil {
+		return nil, err
+	}
+	return &pb.SumResponse{Result: in.FirstNum + in.SecondNum}, nil
+}
+
+// PrimeFactors pretends that in.Num is huge, and takes hugeWork to factor it.
+func (s *server) PrimeFactors(in *pb.PrimeFactorsRequest, stream pb.Math_PrimeFactorsServer) error {
+	if err := s.work(stream.Context(), *hugeWork); err != nil {
+		return err
+	}
+	num := in.Num
+	for factor := int64(2); num > 1; {
+		if num%factor == 0 {
+			if err := stream.Send(&pb.PrimeFactorsResponse{Result: factor}); err != nil {
+				return err
+			}
+			num /= factor
+			continue
+		}
+		factor++
+	}
+	return nil
+}
+
+// startMath starts a Math server on a random port and returns its address.
+func startMath(s *server) (string, error) {
+	bs, err := bootstrap.NewServer(bootstrap.DefaultConfig())
+	if err != nil {
+		return "", err
+	}
+	pb.RegisterMathServer(bs.Server, s)
+	healthcheck.Register(bs.Server)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	go bs.Server.Serve(lis)
+	return lis.Addr().String(), nil
+}
+
+// hog keeps all the workers of the server at addr busy with huge PrimeFactors
+// calls, until ctx is done.
+func hog(ctx context.Context, addr string) error {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	c := pb.NewMathClient(conn)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				stream, err := c.PrimeFactors(ctx, &pb.PrimeFactorsRequest{Num: 1 << 40})
+				if err != nil {
+					continue
+				}
+				for {
+					if _, err := stream.Recv(); err != nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		conn.Close()
+	}()
+	return nil
+}
+
+// result is the outcome of the calls of a policy.
+type result struct {
+	latencies []time.Duration // of the successful calls, sorted
+	errors    int
+	served    map[string]int // the number of calls by server address
+}
+
+func (r *result) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	return r.latencies[int(p*float64(len(r.latencies)-1))]
+}
+
+func (r *result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "p50 %v, p90 %v, p99 %v, max %v, errors %d\n", r.percentile(0.5), r.percentile(0.9), r.percentile(0.99), r.percentile(1), r.errors)
+	var addrs []string
+	for a := range r.served {
+		addrs = append(addrs, a)
+	}
+	sort.Strings(addrs)
+	for _, a := range addrs {
+		fmt.Fprintf(&b, "    %s: %d calls\n", a, r.served[a])
+	}
+	return b.String()
+}
+
+// run makes n Sum calls from callers goroutines, balanced across addrs by
+// policy.
+func run(policy string, addrs []string, callers, n int) (*result, error) {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.Address = strings.Join(addrs, ",")
+	cfg.Client.LoadBalancing = policy
+	conn, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	c := pb.NewMathClient(conn)
+	time.Sleep(500 * time.Millisecond) // the time to connect to the three of them
+
+	r := &result{served: make(map[string]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	calls := make(chan int, n)
+	for i := 0; i < n; i++ {
+		calls <- i
+	}
+	close(calls)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range calls {
+				var p peer.Peer
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				start := time.Now()
+				_, err := c.Sum(ctx, &pb.SumRequest{FirstNum: int32(i), SecondNum: 1}, grpc.Peer(&p))
+				d := time.Since(start)
+				cancel()
+				mu.Lock()
+				if p.Addr != nil {
+					r.served[p.Addr.String()]++
+				}
+				if err != nil {
+					r.errors++
+				} else {
+					r.latencies = append(r.latencies, d)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	return r, nil
+}
+
+func main() {
+	callers := flag.Int("callers", 8, "the number of concurrent callers")
+	calls := flag.Int("calls", 1500, "the number of calls of each policy")
+	flag.Parse()
+
+	var servers []*server
+	var addrs []string
+	for i := 0; i < 3; i++ {
+		s := &server{workers: make(chan struct{}, *workers)}
+		addr, err := startMath(s)
+		if err != nil {
+			log.Fatalf("failed to start server: %v", err)
+		}
+		servers = append(servers, s)
+		addrs = append(addrs, addr)
+	}
+
+	compare := func(title string) (rr, p *result) {
+		fmt.Println(title)
+		var results []*result
+		for _, policy := range []string{"round_robin", p2c.Name} {
+			r, err := run(policy, addrs, *callers, *calls)
+			if err != nil {
+				log.Fatalf("%s: %v", policy, err)
+			}
+			fmt.Printf("  %s: %v", policy, r)
+			results = append(results, r)
+		}
+		return results[0], results[1]
+	}
+	var problems []string
+
+	// 1. Skewed load
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := hog(ctx, addrs[0]); err != nil {
+		log.Fatalf("failed to hog %s: %v", addrs[0], err)
+	}
+	rr, p := compare(fmt.Sprintf("--- 1. %s is busy with huge PrimeFactors requests ---", addrs[0]))
+	cancel()
+	if p.percentile(0.99) >= rr.percentile(0.99)/2 {
+		problems = append(problems, fmt.Sprintf("the p99 latency of p2c is %v, want less than half the %v of round_robin", p.percentile(0.99), rr.percentile(0.99)))
+	}
+
+	// 2. Failing backend
+	atomic.StoreInt32(&servers[2].failing, 1)
+	rr, p = compare(fmt.Sprintf("--- 2. %s answers UNAVAILABLE ---", addrs[2]))
+	if p.errors*10 >= rr.errors {
+		problems = append(problems, fmt.Sprintf("p2c got %d errors, want less than a tenth of the %d of round_robin", p.errors, rr.errors))
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("FAIL: %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
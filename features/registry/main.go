@@ -0,0 +1,263 @@
+// +build !windows
+
+// Package main runs a registry_server and three math_server instances
+// registering in it, and checks the registrations seen by a Watch call and
+// where the calls of a client dialing registry://.../math.Math land:
+//
+//  1. the three instances register at startup, and get calls;
+//  2. an instance shutting down gracefully (SIGTERM) deregisters at once;
+//  3. an instance frozen (SIGSTOP) stops sending heartbeats, and expires
+//     once its TTL has elapsed since the last one;
+//  4. the frozen instance resumed (SIGCONT) registers again.
+//
+// SIGSTOP and SIGCONT are not available on Windows.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/discovery"
+	pb "github.com/wangy8961/grpc-go-tutorial/math/mathpb"
+	registrypb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// event is a change of the registry, received at a time.
+type event struct {
+	*registrypb.Event
+	at time.Time
+}
+
+// watcher receives the changes of the instances of a service.
+type watcher struct {
+	events chan event
+}
+
+func watch(conn *grpc.ClientConn, service string) (*watcher, error) {
+	stream, err := registrypb.NewRegistryClient(conn).Watch(context.Background(), &registrypb.WatchRequest{Service: service}, grpc.WaitForReady(true))
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{events: make(chan event, 16)}
+	go func() {
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				close(w.events)
+				return
+			}
+			for _, e := range resp.Events {
+				w.events <- event{e, time.Now()}
+			}
+		}
+	}()
+	return w, nil
+}
+
+// wait waits for the events of type typ of the instances at addrs, in any
+// order, at most timeout after start, and returns how long after start the last
+// one was received.
+func (w *watcher) wait(start time.Time, timeout time.Duration, typ registrypb.Event_Type, addrs ...string) (time.Duration, error) {
+	missing := make(map[string]bool)
+	for _, a := range addrs {
+		missing[a] = true
+	}
+	var last time.Time
+	t := time.NewTimer(timeout - time.Since(start))
+	defer t.Stop()
+	for len(missing) > 0 {
+		select {
+		case e, ok := <-w.events:
+			if !ok {
+				return 0, fmt.Errorf("the watch ended")
+			}
+			if e.Type == typ && missing[e.Instance.GetAddress()] {
+				delete(missing, e.Instance.GetAddress())
+				last = e.at
+			}
+		case <-t.C:
+			return 0, fmt.Errorf("no %v event within %v", typ, timeout)
+		}
+	}
+	return last.Sub(start), nil
+}
+
+// run starts binary with args, writing its output to the log file.
+func run(logFile *os.File, binary string, args ...string) (*exec.Cmd, error) {
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	return cmd, cmd.Start()
+}
+
+// served makes n Sum calls and returns the ports serving them, sorted.
+func served(c pb.MathClient, n int) ([]string, int) {
+	seen := make(map[string]bool)
+	failed := 0
+	for i := 0; i < n; i++ {
+		var p peer.Peer
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := c.Sum(ctx, &pb.SumRequest{FirstNum: int32(i), SecondNum: 1}, grpc.Peer(&p))
+		cancel()
+		if err != nil {
+			failed++
+			continue
+		}
+		_, port, _ := net.SplitHostPort(p.Addr.String())
+		seen[port] = true
+	}
+	var ports []string
+	for p := range seen {
+		ports = append(ports, p)
+	}
+	sort.Strings(ports)
+	return ports, failed
+}
+
+func main() {
+	registryPort := flag.Int("registry-port", 50070, "the port of the registry")
+	basePort := flag.Int("port", 50071, "the port of the first instance, the others listen on the next ones")
+	ttl := flag.Duration("ttl", 3*time.Second, "the TTL of the instances")
+	calls := flag.Int("calls", 30, "the number of calls of each round")
+	flag.Parse()
+
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	// Stop the processes before exiting, main does not return on failures
+	var cmds []*exec.Cmd
+	cleanup := func() {
+		for _, cmd := range cmds {
+			cmd.Process.Signal(syscall.SIGCONT)
+			cmd.Process.Signal(syscall.SIGTERM)
+			cmd.Wait()
+		}
+		os.RemoveAll(dir)
+	}
+	fatalf := func(format string, v ...interface{}) {
+		cleanup()
+		log.Fatalf(format, v...)
+	}
+
+	for _, name := range []string{"registry/registry_server", "math/math_server"} {
+		build := exec.Command("go", "build", "-o", filepath.Join(dir, filepath.Base(name)), "github.com/wangy8961/grpc-go-tutorial/"+name)
+		build.Stderr = os.Stderr
+		if err := build.Run(); err != nil {
+			fatalf("failed to build %s: %v", name, err)
+		}
+	}
+	logFile, err := os.Create(filepath.Join(dir, "servers.log"))
+	if err != nil {
+		fatalf("failed to create log file: %v", err)
+	}
+	defer logFile.Close()
+
+	registryAddr := fmt.Sprintf("localhost:%d", *registryPort)
+	cmd, err := run(logFile, filepath.Join(dir, "registry_server"), "-port", fmt.Sprint(*registryPort))
+	if err != nil {
+		fatalf("failed to start registry_server: %v", err)
+	}
+	cmds = append(cmds, cmd)
+	regConn, err := grpc.Dial(registryAddr, grpc.WithInsecure())
+	if err != nil {
+		fatalf("did not connect: %v", err)
+	}
+	w, err := watch(regConn, "math.Math")
+	if err != nil {
+		fatalf("failed to watch: %v", err)
+	}
+
+	var problems []string
+	expect := func(title string, start time.Time, within time.Duration, typ registrypb.Event_Type, addrs ...string) {
+		since, err := w.wait(start, within, typ, addrs...)
+		if err != nil {
+			fmt.Printf("%s\n  %v\n", title, err)
+			problems = append(problems, fmt.Sprintf("%s: %v", title, err))
+			return
+		}
+		fmt.Printf("%s\n  %v %s after %v\n", title, typ, strings.Join(addrs, ", "), since.Round(10*time.Millisecond))
+	}
+
+	// 1. Three instances registering at startup
+	var addrs, ports []string
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprint(*basePort + i)
+		cmd, err := run(logFile, filepath.Join(dir, "math_server"),
+			"-port", port,
+			"-admin-port", "0",
+			"-shutdown-grace", "1s",
+			"-registry", registryAddr,
+			"-registry-ttl", ttl.String(),
+		)
+		if err != nil {
+			fatalf("failed to start math_server: %v", err)
+		}
+		cmds = append(cmds, cmd)
+		addrs = append(addrs, "localhost:"+port)
+		ports = append(ports, port)
+	}
+	expect("--- 1. Three instances start ---", start, 10*time.Second, registrypb.Event_ADD, addrs...)
+
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.Address = discovery.RegistryTarget(registryAddr, "math.Math")
+	conn, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		fatalf("did not connect: %v", err)
+	}
+	c := pb.NewMathClient(conn)
+	check := func(want ...string) {
+		got, failed := served(c, *calls)
+		fmt.Printf("  served by: :%s, failed: %d\n", strings.Join(got, ", :"), failed)
+		if strings.Join(got, ",") != strings.Join(want, ",") || failed > 0 {
+			problems = append(problems, fmt.Sprintf("the calls were served by :%s, want :%s", strings.Join(got, ", :"), strings.Join(want, ", :")))
+		}
+	}
+	time.Sleep(500 * time.Millisecond) // the time to connect to the three of them
+	check(ports...)
+
+	// 2. Graceful shutdown, well within the TTL
+	start = time.Now()
+	cmds[1].Process.Signal(syscall.SIGTERM)
+	expect(fmt.Sprintf("--- 2. %s shuts down ---", addrs[0]), start, *ttl/2, registrypb.Event_REMOVE, addrs[0])
+	check(ports[1], ports[2])
+
+	// 3. No heartbeats: expired after the TTL
+	start = time.Now()
+	cmds[2].Process.Signal(syscall.SIGSTOP)
+	expect(fmt.Sprintf("--- 3. %s is frozen ---", addrs[1]), start, *ttl+time.Second, registrypb.Event_REMOVE, addrs[1])
+	check(ports[2])
+
+	// 4. Registered again on its first heartbeat
+	start = time.Now()
+	cmds[2].Process.Signal(syscall.SIGCONT)
+	expect(fmt.Sprintf("--- 4. %s is resumed ---", addrs[1]), start, *ttl/3+time.Second, registrypb.Event_ADD, addrs[1])
+	time.Sleep(500 * time.Millisecond)
+	check(ports[1], ports[2])
+
+	conn.Close()
+	regConn.Close()
+	cleanup()
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("FAIL: %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
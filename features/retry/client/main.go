@@ -0,0 +1,133 @@
+// Package main implements a client for Echo service that retries failed calls.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func unaryCall(c pb.EchoClient, msg string) {
+	fmt.Printf("--- gRPC Unary RPC Call ---\n")
+
+	// The retries must all fit within the deadline of the call
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: msg})
+	if err != nil {
+		fmt.Printf("failed to call UnaryEcho: %v\n", err)
+		return
+	}
+	fmt.Printf("response:\n")
+	fmt.Printf(" - %q\n", resp.GetMessage())
+}
+
+func serverSideStreamingCall(c pb.EchoClient, msg string) {
+	fmt.Printf("--- gRPC Server-side Streaming RPC Call ---\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := c.ServerStreamingEcho(ctx, &pb.EchoRequest{Message: msg})
+	if err != nil {
+		fmt.Printf("failed to call ServerStreamingEcho: %v\n", err)
+		return
+	}
+	fmt.Printf("response:\n")
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Printf("failed to receive: %v\n", err)
+			return
+		}
+		fmt.Printf(" - %q\n", resp.GetMessage())
+	}
+}
+
+func bidirectionalStreamingCall(c pb.EchoClient, msgs ...string) {
+	fmt.Printf("--- gRPC Bidirectional Streaming RPC Call ---\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := c.BidirectionalStreamingEcho(ctx)
+	if err != nil {
+		fmt.Printf("failed to call BidirectionalStreamingEcho: %v\n", err)
+		return
+	}
+	// The messages sent before the first response are sent again if the stream is retried
+	for _, msg := range msgs {
+		if err := stream.Send(&pb.EchoRequest{Message: msg}); err != nil {
+			break
+		}
+	}
+	stream.CloseSend()
+
+	fmt.Printf("response:\n")
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Printf("failed to receive: %v\n", err)
+			return
+		}
+		fmt.Printf(" - %q\n", resp.GetMessage())
+	}
+}
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	// A budget shared by all calls: retries stop when most recent calls fail
+	budget := retry.NewBudget(20, 0.1)
+	retryOpts := []retry.Option{
+		retry.WithMax(4),
+		retry.WithCodes(codes.Unavailable, codes.ResourceExhausted),
+		retry.WithBackoff(100*time.Millisecond, 2*time.Second, 2, 0.2),
+		retry.WithPerAttemptTimeout(time.Second),
+		retry.WithBudget(budget),
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithUnaryInterceptor(retry.UnaryClientInterceptor(retryOpts...)),
+		grpc.WithStreamInterceptor(retry.StreamClientInterceptor(retryOpts...)),
+	}
+
+	// Set up a connection to the server.
+	conn, err := bootstrap.Dial(&cfg.Client, opts...) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+
+	c := pb.NewEchoClient(conn) // Once the gRPC channel is setup, we need a client stub to perform RPCs. We get this using the NewEchoClient method provided in the pb package we generated from our .proto.
+
+	// Contact the server and print out its response.
+	unaryCall(c, fmt.Sprintf("unary %d", time.Now().Unix()))
+	fmt.Println()
+	serverSideStreamingCall(c, fmt.Sprintf("stream %d", time.Now().Unix()))
+	fmt.Println()
+	bidirectionalStreamingCall(c, fmt.Sprintf("bidi %d", time.Now().Unix()), "hello", "world")
+}
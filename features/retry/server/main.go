@@ -0,0 +1,130 @@
+// Package main implements a flaky server for Echo service, to try out the retry interceptors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// server is used to implement echopb.EchoServer.
+// It fails the first calls of every message before answering it.
+type server struct {
+	echoserver.Server
+
+	failures int           // number of failed calls per message
+	pushback time.Duration // RetryInfo delay of the ResourceExhausted failures
+
+	mu       sync.Mutex
+	attempts map[string]int // message -> number of calls so far
+}
+
+// fail returns the error of the current call with msg, or nil once msg has failed enough.
+// Failures alternate between Unavailable and ResourceExhausted with a RetryInfo.
+func (s *server) fail(msg string) error {
+	s.mu.Lock()
+	s.attempts[msg]++
+	n := s.attempts[msg]
+	s.mu.Unlock()
+
+	if n > s.failures {
+		fmt.Printf("call %d with %q: succeeded\n", n, msg)
+		return nil
+	}
+	if n%2 == 1 {
+		fmt.Printf("call %d with %q: failing with Unavailable\n", n, msg)
+		return status.Errorf(codes.Unavailable, "simulated failure %d of %d", n, s.failures)
+	}
+	fmt.Printf("call %d with %q: failing with ResourceExhausted, retry in %v\n", n, msg, s.pushback)
+	st, err := status.New(codes.ResourceExhausted, "simulated overload").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(s.pushback),
+	})
+	if err != nil {
+		return status.Errorf(codes.ResourceExhausted, "simulated overload")
+	}
+	return st.Err()
+}
+
+func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	fmt.Printf("--- gRPC Unary RPC ---\n")
+	if err := s.fail(req.GetMessage()); err != nil {
+		return nil, err
+	}
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+func (s *server) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
+	fmt.Printf("--- gRPC Server-side Streaming RPC ---\n")
+	if err := s.fail(req.GetMessage()); err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&pb.EchoResponse{Message: req.GetMessage()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) BidirectionalStreamingEcho(stream pb.Echo_BidirectionalStreamingEchoServer) error {
+	fmt.Printf("--- gRPC Bidirectional Streaming RPC ---\n")
+	first := true
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Only the beginning of the stream fails, so that the client can retry it
+		if first {
+			if err := s.fail(in.GetMessage()); err != nil {
+				return err
+			}
+			first = false
+		}
+		if err := stream.Send(&pb.EchoResponse{Message: in.GetMessage()}); err != nil {
+			return err
+		}
+	}
+}
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Interceptors.Logging = true // Log one JSON line per call
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	failures := flag.Int("failures", 2, "the number of failed calls per message")
+	pushback := flag.Duration("pushback", 300*time.Millisecond, "the retry delay asked by ResourceExhausted failures")
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	s, err := bootstrap.NewServer(cfg) // Create an instance of the gRPC server
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+	pb.RegisterEchoServer(s.Server, &server{
+		failures: *failures,
+		pushback: *pushback,
+		attempts: make(map[string]int),
+	}) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil { // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
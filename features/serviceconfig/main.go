@@ -0,0 +1,213 @@
+// Package main calls an Echo server running in the same process through
+// clients configured by a service config only, with no timeout or call option
+// in the code, and checks that the calls follow it:
+//
+//  1. timeout: a call taking longer than the timeout of its method fails with
+//     DEADLINE_EXCEEDED once the timeout has elapsed;
+//  2. maxRequestMessageBytes: a request larger than the limit of its method
+//     fails with RESOURCE_EXHAUSTED before it is sent;
+//  3. retryPolicy: a call failing with UNAVAILABLE once succeeds on the next
+//     attempt, when the retries are enabled with GRPC_GO_RETRY=on;
+//  4. waitForReady: a call made before the server starts waits for it,
+//     rather than failing with UNAVAILABLE at once;
+//  5. a service config delivered by the resolver, in the endpoints file of a
+//     file:// target, overrides the one of the client.
+//
+// Run it with GRPC_GO_RETRY=on to check the retries too.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serviceConfig is the service config file of the clients.
+const serviceConfig = `{
+  "methodConfig": [
+    {
+      "name": [{"service": "echo.Echo", "method": "UnaryEcho"}],
+      "timeout": "2s",
+      "waitForReady": true,
+      "retryPolicy": {
+        "maxAttempts": 3,
+        "initialBackoff": "0.01s",
+        "maxBackoff": "0.1s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE"]
+      },
+      "maxRequestMessageBytes": 1024
+    }
+  ]
+}`
+
+// endpoints is the endpoints file of the file:// target, with a shorter
+// timeout than serviceConfig.
+const endpoints = `endpoints:
+  - address: %s
+service_config: |
+  {
+    "loadBalancingPolicy": "round_robin",
+    "methodConfig": [{"name": [{"service": "echo.Echo", "method": "UnaryEcho"}], "timeout": "0.1s"}]
+  }
+`
+
+// server answers UnaryEcho quietly, after 3s when the message is
+// "slow", and fails every other call with UNAVAILABLE when it is "flaky".
+type server struct {
+	echoserver.Server
+	flaky int32
+}
+
+func (s *server) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	switch req.GetMessage() {
+	case "slow":
+		select {
+		case <-time.After(3 * time.Second):
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	case "flaky":
+		if atomic.AddInt32(&s.flaky, 1)%2 == 1 {
+			return nil, status.Errorf(codes.Unavailable, "try again")
+		}
+	}
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// startEcho starts an Echo server on lis.
+func startEcho(lis net.Listener) error {
+	s, err := bootstrap.NewServer(bootstrap.DefaultConfig())
+	if err != nil {
+		return err
+	}
+	pb.RegisterEchoServer(s.Server, &server{})
+	healthcheck.Register(s.Server)
+	go s.Server.Serve(lis)
+	return nil
+}
+
+// call makes a UnaryEcho call with msg, and returns its code and duration.
+func call(c pb.EchoClient, msg string) (codes.Code, time.Duration) {
+	start := time.Now()
+	_, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: msg})
+	return status.Code(err), time.Since(start)
+}
+
+func main() {
+	retries := strings.EqualFold(os.Getenv("GRPC_GO_RETRY"), "on")
+
+	dir, err := ioutil.TempDir("", "serviceconfig")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	scPath := filepath.Join(dir, "service_config.json")
+	if err := ioutil.WriteFile(scPath, []byte(serviceConfig), 0644); err != nil {
+		log.Fatalf("failed to write service config: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	if err := startEcho(lis); err != nil {
+		log.Fatalf("failed to start server: %v", err)
+	}
+
+	var problems []string
+	check := func(title string, got codes.Code, d time.Duration, want codes.Code, min, max time.Duration) {
+		fmt.Printf("%s\n  %v after %v\n", title, got, d.Round(10*time.Millisecond))
+		if got != want || d < min || d > max {
+			problems = append(problems, fmt.Sprintf("%s: got %v after %v, want %v after %v to %v", title, got, d, want, min, max))
+		}
+	}
+
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.Address = lis.Addr().String()
+	cfg.Client.ServiceConfigFile = scPath
+	conn, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+	c := pb.NewEchoClient(conn)
+
+	// 1. timeout
+	got, d := call(c, "slow")
+	check("--- 1. A call taking 3s, with a timeout of 2s ---", got, d, codes.DeadlineExceeded, 2*time.Second, 2300*time.Millisecond)
+
+	// 2. maxRequestMessageBytes
+	got, d = call(c, strings.Repeat("x", 2048))
+	check("--- 2. A request of 2KB, with a limit of 1KB ---", got, d, codes.ResourceExhausted, 0, 100*time.Millisecond)
+
+	// 3. retryPolicy
+	got, d = call(c, "flaky")
+	if retries {
+		check("--- 3. A call failing once, retried ---", got, d, codes.OK, 0, 200*time.Millisecond)
+	} else {
+		check("--- 3. A call failing once, not retried without GRPC_GO_RETRY=on ---", got, d, codes.Unavailable, 0, 100*time.Millisecond)
+	}
+
+	// 4. waitForReady
+	lis2, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	addr := lis2.Addr().String()
+	lis2.Close()
+	cfg.Client.Address = addr
+	conn2, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn2.Close()
+	time.AfterFunc(200*time.Millisecond, func() {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		if err := startEcho(lis); err != nil {
+			log.Fatalf("failed to start server: %v", err)
+		}
+	})
+	got, d = call(pb.NewEchoClient(conn2), "madmalls")
+	// gRPC connects again 1s after the first failure
+	check("--- 4. A call made 0.2s before the server starts ---", got, d, codes.OK, 200*time.Millisecond, 2*time.Second)
+
+	// 5. The service config of the resolver
+	epPath := filepath.Join(dir, "endpoints.yaml")
+	if err := ioutil.WriteFile(epPath, []byte(fmt.Sprintf(endpoints, lis.Addr())), 0644); err != nil {
+		log.Fatalf("failed to write endpoints: %v", err)
+	}
+	cfg.Client.Address = "file://" + epPath
+	conn3, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn3.Close()
+	got, d = call(pb.NewEchoClient(conn3), "slow")
+	check("--- 5. A call taking 3s, with a timeout of 0.1s set by the endpoints file ---", got, d, codes.DeadlineExceeded, 100*time.Millisecond, 400*time.Millisecond)
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("FAIL: %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
@@ -0,0 +1,87 @@
+package simulate
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys that override the config for a call.
+const (
+	LatencyKey  = "x-simulate-latency"
+	WorkKey     = "x-simulate-work"
+	ErrorsKey   = "x-simulate-errors"
+	IntervalKey = "x-simulate-interval"
+)
+
+var metadataKeys = map[string]string{
+	"latency":  LatencyKey,
+	"work":     WorkKey,
+	"errors":   ErrorsKey,
+	"interval": IntervalKey,
+}
+
+// For returns the spec of a call of fullMethod, from c and the metadata of ctx.
+func (c Config) For(ctx context.Context, fullMethod string) (Spec, error) {
+	spec := c.lookup(fullMethod)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, key := range metadataKeys {
+		if v := md.Get(key); len(v) > 0 {
+			if err := spec.set(k, v[0]); err != nil {
+				return Spec{}, status.Errorf(codes.InvalidArgument, "%s: %v", key, err)
+			}
+		}
+	}
+	return spec, nil
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that simulates
+// the work of the calls before handling them.
+func UnaryServerInterceptor(c Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		spec, err := c.For(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if err := spec.Work(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that
+// simulates the work of the streams before handling them, and paces the
+// messages they send.
+func StreamServerInterceptor(c Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		spec, err := c.For(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if err := spec.Work(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, spec: spec})
+	}
+}
+
+// serverStream wraps grpc.ServerStream to pace the messages it sends.
+type serverStream struct {
+	grpc.ServerStream
+	spec Spec
+	sent bool
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	if s.sent {
+		if err := s.spec.Pace(s.Context()); err != nil {
+			return err
+		}
+	}
+	s.sent = true
+	return s.ServerStream.SendMsg(m)
+}
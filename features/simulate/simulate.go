@@ -0,0 +1,216 @@
+// Package simulate lets Echo servers simulate work, to try out deadlines,
+// cancellation, retries and hedging: every method can take a random latency,
+// spent sleeping or burning CPU, fail at a given rate and pace the messages of
+// its streams. The simulation is set per method by a config file, and can be
+// overridden per call with request metadata:
+//
+//	x-simulate-latency:  fixed:100ms | uniform:10ms,200ms | normal:100ms,20ms | longtail:10ms,0.05,1s
+//	x-simulate-work:     sleep | cpu
+//	x-simulate-errors:   UNAVAILABLE=0.1,INTERNAL=0.01
+//	x-simulate-interval: 100ms
+//
+// A long-tail latency of "10ms,0.05,1s" takes 10ms, except 5% of the time 1s.
+// Since any client can make the server burn CPU, this is for testing only.
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Spec is the simulation of a method.
+type Spec struct {
+	Latency  Latency                // how long the method takes
+	CPU      bool                   // burn CPU instead of sleeping
+	Errors   map[codes.Code]float64 // the rate at which the method fails with each code
+	Interval time.Duration          // the pause between the messages sent on a stream
+}
+
+// Latency is a distribution of latencies.
+type Latency struct {
+	Kind     string        // "fixed", "uniform", "normal" or "longtail", no latency if empty
+	A, B     time.Duration // fixed: A; uniform: from A to B; normal: mean A, stddev B; longtail: A, or B at TailRate
+	TailRate float64
+}
+
+// Config holds the simulation of the methods, by full method name (e.g.
+// "/echo.Echo/UnaryEcho") or method name (e.g. "UnaryEcho"). The "*" entry
+// applies to the other methods.
+type Config map[string]Spec
+
+// Load reads a JSON config file such as:
+//
+//	{
+//	    "UnaryEcho": {"latency": "normal:3s,500ms", "work": "cpu", "errors": {"UNAVAILABLE": 0.1}},
+//	    "ServerStreamingEcho": {"interval": "200ms"}
+//	}
+func Load(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]struct {
+		Latency  string             `json:"latency"`
+		Work     string             `json:"work"`
+		Errors   map[string]float64 `json:"errors"`
+		Interval string             `json:"interval"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	c := make(Config)
+	for method, f := range file {
+		var spec Spec
+		fields := map[string]string{"latency": f.Latency, "work": f.Work, "interval": f.Interval}
+		for _, k := range []string{"latency", "work", "interval"} {
+			if err := spec.set(k, fields[k]); err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", path, method, err)
+			}
+		}
+		for name, rate := range f.Errors {
+			code, ok := parseCode(name)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s: unknown status code %q", path, method, name)
+			}
+			if spec.Errors == nil {
+				spec.Errors = make(map[codes.Code]float64)
+			}
+			spec.Errors[code] = rate
+		}
+		c[method] = spec
+	}
+	return c, nil
+}
+
+// lookup returns the spec of fullMethod.
+func (c Config) lookup(fullMethod string) Spec {
+	if spec, ok := c[fullMethod]; ok {
+		return spec
+	}
+	if spec, ok := c[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]; ok {
+		return spec
+	}
+	return c["*"]
+}
+
+// set sets the field k of s from its text form, ignoring empty values.
+func (s *Spec) set(k, v string) error {
+	if v == "" {
+		return nil
+	}
+	switch k {
+	case "latency":
+		l, err := ParseLatency(v)
+		if err != nil {
+			return err
+		}
+		s.Latency = l
+	case "work":
+		switch v {
+		case "sleep":
+			s.CPU = false
+		case "cpu":
+			s.CPU = true
+		default:
+			return fmt.Errorf("invalid work %q, want sleep or cpu", v)
+		}
+	case "errors":
+		errs, err := parseErrors(v)
+		if err != nil {
+			return err
+		}
+		s.Errors = errs
+	case "interval":
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		s.Interval = d
+	}
+	return nil
+}
+
+// ParseLatency parses a latency distribution, e.g. "uniform:10ms,200ms".
+func ParseLatency(s string) (Latency, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return Latency{}, fmt.Errorf("invalid latency %q, want kind:params", s)
+	}
+	l := Latency{Kind: s[:i]}
+	params := strings.Split(s[i+1:], ",")
+	var err error
+	switch {
+	case l.Kind == "fixed" && len(params) == 1:
+		l.A, err = time.ParseDuration(params[0])
+	case (l.Kind == "uniform" || l.Kind == "normal") && len(params) == 2:
+		if l.A, err = time.ParseDuration(params[0]); err == nil {
+			l.B, err = time.ParseDuration(params[1])
+		}
+	case l.Kind == "longtail" && len(params) == 3:
+		if l.A, err = time.ParseDuration(params[0]); err == nil {
+			if l.TailRate, err = strconv.ParseFloat(params[1], 64); err == nil {
+				l.B, err = time.ParseDuration(params[2])
+			}
+		}
+	default:
+		return Latency{}, fmt.Errorf("invalid latency %q", s)
+	}
+	if err == nil {
+		err = l.validate()
+	}
+	if err != nil {
+		return Latency{}, fmt.Errorf("invalid latency %q: %v", s, err)
+	}
+	return l, nil
+}
+
+// validate checks the parameters of the distribution, which sample relies on.
+func (l Latency) validate() error {
+	switch {
+	case l.Kind == "uniform" && l.B < l.A:
+		return fmt.Errorf("the upper bound %v is less than the lower bound %v", l.B, l.A)
+	case l.Kind == "normal" && l.B < 0:
+		return fmt.Errorf("negative standard deviation %v", l.B)
+	case l.Kind == "longtail" && !(l.TailRate >= 0 && l.TailRate <= 1):
+		return fmt.Errorf("the tail rate %v is not between 0 and 1", l.TailRate)
+	}
+	return nil
+}
+
+// parseErrors parses error rates, e.g. "UNAVAILABLE=0.1,INTERNAL=0.01".
+func parseErrors(s string) (map[codes.Code]float64, error) {
+	errs := make(map[codes.Code]float64)
+	for _, e := range strings.Split(s, ",") {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid error rate %q, want CODE=rate", e)
+		}
+		code, ok := parseCode(kv[0])
+		if !ok {
+			return nil, fmt.Errorf("unknown status code %q", kv[0])
+		}
+		rate, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error rate %q: %v", e, err)
+		}
+		errs[code] = rate
+	}
+	return errs, nil
+}
+
+// parseCode parses a status code name, either "RESOURCE_EXHAUSTED" or "ResourceExhausted".
+func parseCode(name string) (codes.Code, bool) {
+	name = strings.ToLower(strings.Replace(name, "_", "", -1))
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.ToLower(c.String()) == name {
+			return c, true
+		}
+	}
+	return 0, false
+}
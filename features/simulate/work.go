@@ -0,0 +1,99 @@
+package simulate
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sample returns a random latency of the distribution.
+func (l Latency) sample() time.Duration {
+	var d time.Duration
+	switch l.Kind {
+	case "fixed":
+		d = l.A
+	case "uniform":
+		d = l.A + time.Duration(rand.Int63n(int64(l.B-l.A)+1))
+	case "normal":
+		d = l.A + time.Duration(rand.NormFloat64()*float64(l.B))
+	case "longtail":
+		d = l.A
+		if rand.Float64() < l.TailRate {
+			d = l.B
+		}
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// Work simulates the work of a call: it takes a latency of the distribution,
+// then fails at the error rates of the spec. It returns early with the error
+// of ctx if the call is canceled or its deadline is exceeded.
+func (s Spec) Work(ctx context.Context) error {
+	d := s.Latency.sample()
+	if s.CPU {
+		if err := burn(ctx, d); err != nil {
+			return err
+		}
+	} else if err := sleep(ctx, d); err != nil {
+		return err
+	}
+	return s.inject()
+}
+
+// Pace waits for the interval between two messages of a stream.
+func (s Spec) Pace(ctx context.Context) error {
+	return sleep(ctx, s.Interval)
+}
+
+// inject returns an error at the rates of the spec.
+func (s Spec) inject() error {
+	r := rand.Float64()
+	for code, rate := range s.Errors {
+		if r < rate {
+			return status.Errorf(code, "simulated %v error", code)
+		}
+		r -= rate
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// burn keeps a CPU busy for d, checking ctx every millisecond.
+func burn(ctx context.Context, d time.Duration) error {
+	end := time.Now().Add(d)
+	x := 1.0
+	for time.Now().Before(end) {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		for slice := time.Now().Add(time.Millisecond); time.Now().Before(slice); {
+			for i := 0; i < 1000; i++ {
+				x = x*1.0000001 + 1e-9
+			}
+		}
+	}
+	if x == 0 {
+		// Never true, keeps the compiler from dropping the loop
+		return status.Errorf(codes.Internal, "burn: %v", x)
+	}
+	return nil
+}
@@ -7,24 +7,24 @@ import (
 	"fmt"
 	"log"
 
-	"google.golang.org/grpc/credentials"
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 
 	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
-	"google.golang.org/grpc"
 )
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
-	certFile := flag.String("cacert", "cacert.pem", "CA root certificate")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.TLS.CAFile = "cacert.pem"
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	creds, err := credentials.NewClientTLSFromFile(*certFile, "")
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to load CA root certificate: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
 
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(creds)) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	conn, err := bootstrap.Dial(&cfg.Client) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -0,0 +1,202 @@
+// Package main traces the calls of an Echo client to an Echo server running
+// in the same process, and checks the spans kept by the in-memory exporter:
+// every call must have a client span and a server span in the same trace, the
+// server span being a child of the client one, with an event per message.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"github.com/wangy8961/grpc-go-tutorial/tracing"
+	"google.golang.org/grpc"
+)
+
+func unaryCall(ctx context.Context, c pb.EchoClient) {
+	if _, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "madmalls.com"}); err != nil {
+		log.Fatalf("failed to call UnaryEcho: %v", err)
+	}
+}
+
+func serverStreamingCall(ctx context.Context, c pb.EchoClient) {
+	stream, err := c.ServerStreamingEcho(ctx, &pb.EchoRequest{Message: "madmalls.com"})
+	if err != nil {
+		log.Fatalf("failed to call ServerStreamingEcho: %v", err)
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatalf("failed to finish server streaming: %v", err)
+		}
+	}
+}
+
+func clientStreamingCall(ctx context.Context, c pb.EchoClient) {
+	stream, err := c.ClientStreamingEcho(ctx)
+	if err != nil {
+		log.Fatalf("failed to call ClientStreamingEcho: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&pb.EchoRequest{Message: fmt.Sprintf("Request %d", i+1)}); err != nil {
+			log.Fatalf("failed to send request due to error: %v", err)
+		}
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		log.Fatalf("failed to finish client streaming: %v", err)
+	}
+}
+
+func bidirectionalStreamingCall(ctx context.Context, c pb.EchoClient) {
+	stream, err := c.BidirectionalStreamingEcho(ctx)
+	if err != nil {
+		log.Fatalf("failed to call BidirectionalStreamingEcho: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&pb.EchoRequest{Message: fmt.Sprintf("Request %d", i+1)}); err != nil {
+			log.Fatalf("failed to send request due to error: %v", err)
+		}
+	}
+	stream.CloseSend()
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatalf("failed to finish bidirectional streaming: %v", err)
+		}
+	}
+}
+
+// check returns the problems of the spans of the trace.
+func check(spans []tracing.SpanData) []string {
+	var problems []string
+	byID := make(map[string]tracing.SpanData)
+	for _, s := range spans {
+		byID[s.SpanID] = s
+	}
+	servers := 0
+	for _, s := range spans {
+		if s.Kind != tracing.Server {
+			continue
+		}
+		servers++
+		parent, ok := byID[s.ParentSpanID]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("server span %s has no parent", s.Name))
+		case parent.Kind != tracing.Client || parent.Name != s.Name:
+			problems = append(problems, fmt.Sprintf("server span %s has parent %s %s", s.Name, parent.Kind, parent.Name))
+		case parent.TraceID != s.TraceID:
+			problems = append(problems, fmt.Sprintf("server span %s is not in the trace of its parent", s.Name))
+		}
+		if len(s.Events) == 0 {
+			problems = append(problems, fmt.Sprintf("server span %s has no message events", s.Name))
+		}
+	}
+	if servers != 4 {
+		problems = append(problems, fmt.Sprintf("got %d server spans, want 4", servers))
+	}
+	return problems
+}
+
+// printTree prints the spans as a tree, the children under their parent.
+func printTree(spans []tracing.SpanData) {
+	children := make(map[string][]tracing.SpanData)
+	for _, s := range spans {
+		children[s.ParentSpanID] = append(children[s.ParentSpanID], s)
+	}
+	var walk func(parent string, depth int)
+	walk = func(parent string, depth int) {
+		for _, s := range children[parent] {
+			indent := strings.Repeat("  ", depth)
+			fmt.Printf("%s%s [%s] %.2fms %s\n", indent, s.Name, s.Kind, s.DurationMS, s.Status.Code)
+			for _, e := range s.Events {
+				fmt.Printf("%s  - %s %s #%s (%s bytes)\n", indent, e.Name, e.Attributes["message.type"], e.Attributes["message.id"], e.Attributes["message.uncompressed_size"])
+			}
+			walk(s.SpanID, depth+1)
+		}
+	}
+	walk("", 0)
+}
+
+func main() {
+	traceFile := flag.String("trace-file", "", "the file the spans are also appended to as JSON lines")
+	flag.Parse()
+
+	// The spans are kept in memory, no collector is needed
+	exporter := &tracing.InMemoryExporter{}
+	var exporters multiExporter = []tracing.Exporter{exporter}
+	if *traceFile != "" {
+		fileExporter, f, err := tracing.NewFileExporter(*traceFile)
+		if err != nil {
+			log.Fatalf("failed to open trace file: %v", err)
+		}
+		defer f.Close()
+		exporters = append(exporters, fileExporter)
+	}
+	tracer := tracing.NewTracer(exporters)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(tracer.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(tracer.StreamServerInterceptor()),
+	)
+	pb.RegisterEchoServer(s, &echoserver.Server{Repeat: 3})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(tracer.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(tracer.StreamClientInterceptor()),
+	)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+	c := pb.NewEchoClient(conn)
+
+	// All the calls are children of one root span
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx, root := tracer.Start(ctx, "echo-calls", tracing.Internal)
+	unaryCall(ctx, c)
+	serverStreamingCall(ctx, c)
+	clientStreamingCall(ctx, c)
+	bidirectionalStreamingCall(ctx, c)
+	root.End()
+
+	// The server spans end after the client ones have received the status
+	time.Sleep(100 * time.Millisecond)
+
+	spans := exporter.Spans()
+	fmt.Printf("--- Trace %s ---\n", root.SpanContext().TraceID)
+	printTree(spans)
+	if problems := check(spans); len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("FAIL: %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
+
+// multiExporter exports the spans to all its exporters.
+type multiExporter []tracing.Exporter
+
+func (m multiExporter) Export(span tracing.SpanData) {
+	for _, e := range m {
+		e.Export(span)
+	}
+}
@@ -1,9 +1,10 @@
 module github.com/wangy8961/grpc-go-tutorial
 
-go 1.12
+go 1.13
 
 require (
 	cloud.google.com/go v0.40.0 // indirect
+	github.com/BurntSushi/toml v0.4.1
 	github.com/elazarl/go-bindata-assetfs v1.0.0
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
 	github.com/golang/mock v1.3.1 // indirect
@@ -13,6 +14,7 @@ require (
 	github.com/googleapis/gax-go/v2 v2.0.5 // indirect
 	github.com/grpc-ecosystem/go-grpc-middleware v1.0.0
 	github.com/grpc-ecosystem/grpc-gateway v1.9.1
+	github.com/jhump/protoreflect v1.6.0
 	github.com/kr/pty v1.1.5 // indirect
 	github.com/philips/go-bindata-assetfs v0.0.0-20150624150248-3dcc96556217
 	github.com/philips/grpc-gateway-example v0.0.0-20170619012617-a269bcb5931c
@@ -22,7 +24,7 @@ require (
 	golang.org/x/image v0.0.0-20190523035834-f03afa92d3ff // indirect
 	golang.org/x/mobile v0.0.0-20190607214518-6fa95d984e88 // indirect
 	golang.org/x/mod v0.1.0 // indirect
-	golang.org/x/net v0.0.0-20190613194153-d28f0bde5980 // indirect
+	golang.org/x/net v0.0.0-20190613194153-d28f0bde5980
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
 	golang.org/x/sys v0.0.0-20190616124812-15dcb6c0061f // indirect
 	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4 // indirect
@@ -30,5 +32,6 @@ require (
 	google.golang.org/appengine v1.6.1 // indirect
 	google.golang.org/genproto v0.0.0-20190611190212-a7e196e89fd3
 	google.golang.org/grpc v1.21.1
+	gopkg.in/yaml.v2 v2.4.0
 	honnef.co/go/tools v0.0.0-20190614002413-cb51c254f01b // indirect
 )
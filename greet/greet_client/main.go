@@ -3,22 +3,34 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"os"
-	"time"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/greet/greetpb"
+	"github.com/wangy8961/grpc-go-tutorial/hedging"
 	"google.golang.org/grpc"
 )
 
-const (
-	address     = "localhost:50051"
-	defaultName = "world"
-)
+const defaultName = "world"
 
 func main() {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.ServiceConfigFile = bootstrap.SourcePath("service_config.json") // SayHello 的超时时长为 1 秒
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	flag.Parse()
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(address, grpc.WithInsecure()) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	// Hedge the slow SayHello calls, at most 10% more requests
+	hedge := hedging.UnaryClientInterceptor(
+		hedging.WithMethods("/greet.Greeter/SayHello"),
+		hedging.WithBudget(hedging.NewBudget(0.1, 10)),
+	)
+	conn, err := bootstrap.Dial(&cfg.Client, grpc.WithUnaryInterceptor(hedge)) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -27,12 +39,10 @@ func main() {
 
 	// Contact the server and print out its response.
 	name := defaultName
-	if len(os.Args) > 1 {
-		name = os.Args[1]
+	if flag.NArg() > 0 {
+		name = flag.Arg(0)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	r, err := c.SayHello(ctx, &pb.HelloRequest{Name: name}) // Now let’s look at how we call our service methods. Note that in gRPC-Go, RPCs operate in a blocking/synchronous mode, which means that the RPC call waits for the server to respond, and will either return a response or an error.
+	r, err := c.SayHello(context.Background(), &pb.HelloRequest{Name: name}) // Now let’s look at how we call our service methods. Note that in gRPC-Go, RPCs operate in a blocking/synchronous mode, which means that the RPC call waits for the server to respond, and will either return a response or an error.
 	if err != nil {
 		log.Fatalf("could not greet: %v", err)
 	}
@@ -3,15 +3,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"net"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 	pb "github.com/wangy8961/grpc-go-tutorial/greet/greetpb"
-	"google.golang.org/grpc"
-)
-
-const (
-	port = ":50051"
 )
 
 // server is used to implement greetpb.GreeterServer.
@@ -24,13 +20,22 @@ func (s *server) SayHello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloRe
 }
 
 func main() {
-	lis, err := net.Listen("tcp", port) // Specify the port we want to use to listen for client requests
+	cfg := bootstrap.DefaultConfig()
+	cfg.Admin.Address = ":9090" // serves /metrics
+	cfg.Server.Interceptors.Metrics = true
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	s, err := bootstrap.NewServer(cfg) // Create an instance of the gRPC server
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to create server: %v", err)
 	}
-	s := grpc.NewServer()                  // Create an instance of the gRPC server
-	pb.RegisterGreeterServer(s, &server{}) // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil {   // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	pb.RegisterGreeterServer(s.Server, &server{}) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {             // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
@@ -0,0 +1,423 @@
+// Package main implements grpccli, a command-line client that discovers the
+// services of a server with the gRPC server reflection service, so that any
+// method can be called without writing a client:
+//
+//	grpccli -addr localhost:50051 list
+//	grpccli -addr localhost:50051 list echo.Echo
+//	grpccli -addr localhost:50051 describe echo.EchoRequest
+//	grpccli -addr localhost:50051 call echo.Echo/UnaryEcho '{"message": "madmalls.com"}'
+//	echo '{"message": "a"} {"message": "b"}' | grpccli -addr localhost:50051 call echo.Echo/BidirectionalStreamingEcho
+//
+// The request messages are JSON objects, from the arguments following the
+// method or else from stdin, and the responses are printed as JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	// Register the error details types, to print them in the errors
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const usage = `Usage: grpccli [flags] <command> [args]
+
+Commands:
+  list [service]             list the services, or the methods of a service
+  describe <symbol>          describe a service, method, message or enum
+  call <method> [json...]    call a method, e.g. echo.Echo/UnaryEcho, with the
+                             request messages from the arguments or else stdin
+
+Flags:
+`
+
+// headers are the -H flags, "key: value" metadata sent with the calls.
+type headers []string
+
+func (h *headers) String() string { return strings.Join(*h, ", ") }
+
+func (h *headers) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return fmt.Errorf("header %q is not \"key: value\"", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// metadata returns the headers as outgoing metadata pairs.
+func (h headers) metadata() metadata.MD {
+	md := metadata.MD{}
+	for _, kv := range h {
+		i := strings.Index(kv, ":")
+		md.Append(strings.ToLower(strings.TrimSpace(kv[:i])), strings.TrimSpace(kv[i+1:]))
+	}
+	return md
+}
+
+func main() {
+	flags := bootstrap.ClientFlags(flag.CommandLine, bootstrap.DefaultConfig())
+	timeout := flag.Duration("timeout", 10*time.Second, "the timeout of the command")
+	verbose := flag.Bool("v", false, "print the response headers and trailers")
+	var hdrs headers
+	flag.Var(&hdrs, "H", `a "key: value" header sent with the calls, may be repeated`)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	conn, err := bootstrap.Dial(&cfg.Client)
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	// The reflection calls carry the headers too, the servers may require credentials for them
+	ctx = metadata.NewOutgoingContext(ctx, hdrs.metadata())
+	rc := grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(conn))
+	defer rc.Reset()
+
+	args := flag.Args()[1:]
+	switch cmd := flag.Arg(0); cmd {
+	case "list":
+		err = list(rc, args)
+	case "describe":
+		err = describe(rc, args)
+	case "call":
+		c := &caller{conn: conn, rc: rc, verbose: *verbose, out: os.Stdout}
+		err = c.call(ctx, args, os.Stdin)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, formatError(err, anyResolver{rc}))
+		os.Exit(1)
+	}
+}
+
+// list prints the services, or the methods of the service args[0].
+func list(rc *grpcreflect.Client, args []string) error {
+	if len(args) == 0 {
+		services, err := rc.ListServices()
+		if err != nil {
+			return err
+		}
+		sort.Strings(services)
+		for _, s := range services {
+			fmt.Println(s)
+		}
+		return nil
+	}
+	sd, err := rc.ResolveService(args[0])
+	if err != nil {
+		return err
+	}
+	for _, md := range sd.GetMethods() {
+		fmt.Println(methodSignature(md))
+	}
+	return nil
+}
+
+// methodSignature returns the signature of a method, e.g.
+// "echo.Echo/ClientStreamingEcho(stream echo.EchoRequest) returns (echo.EchoResponse)".
+func methodSignature(md *desc.MethodDescriptor) string {
+	stream := func(b bool) string {
+		if b {
+			return "stream "
+		}
+		return ""
+	}
+	return fmt.Sprintf("%s/%s(%s%s) returns (%s%s)", md.GetService().GetFullyQualifiedName(), md.GetName(),
+		stream(md.IsClientStreaming()), md.GetInputType().GetFullyQualifiedName(),
+		stream(md.IsServerStreaming()), md.GetOutputType().GetFullyQualifiedName())
+}
+
+// resolve returns the descriptor of a symbol, such as "echo.Echo",
+// "echo.Echo/UnaryEcho", "echo.Echo.UnaryEcho" or "echo.EchoRequest".
+func resolve(rc *grpcreflect.Client, symbol string) (desc.Descriptor, error) {
+	symbol = strings.Replace(strings.TrimPrefix(symbol, "/"), "/", ".", 1)
+	fd, err := rc.FileContainingSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	d := fd.FindSymbol(symbol)
+	if d == nil {
+		return nil, fmt.Errorf("symbol %q not found in %s", symbol, fd.GetName())
+	}
+	return d, nil
+}
+
+// describe prints the definition of the symbol args[0] as in its proto file.
+func describe(rc *grpcreflect.Client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("describe needs a symbol, e.g. echo.Echo")
+	}
+	d, err := resolve(rc, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is a %s in %s:\n", d.GetFullyQualifiedName(), kind(d), d.GetFile().GetName())
+	p := &protoprint.Printer{Compact: true}
+	s, err := p.PrintProtoToString(d)
+	if err != nil {
+		return err
+	}
+	fmt.Print(s)
+	return nil
+}
+
+func kind(d desc.Descriptor) string {
+	switch d.(type) {
+	case *desc.ServiceDescriptor:
+		return "service"
+	case *desc.MethodDescriptor:
+		return "method"
+	case *desc.MessageDescriptor:
+		return "message"
+	case *desc.EnumDescriptor:
+		return "enum"
+	case *desc.FieldDescriptor:
+		return "field"
+	default:
+		return "symbol"
+	}
+}
+
+// anyResolver resolves the types of the Any fields, such as the error details,
+// with the types linked in grpccli, or else with the reflection service.
+type anyResolver struct {
+	rc *grpcreflect.Client
+}
+
+func (r anyResolver) Resolve(typeURL string) (proto.Message, error) {
+	name := typeURL[strings.LastIndex(typeURL, "/")+1:]
+	if t := proto.MessageType(name); t != nil {
+		return reflect.New(t.Elem()).Interface().(proto.Message), nil
+	}
+	md, err := r.rc.ResolveMessage(name)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewMessage(md), nil
+}
+
+// caller calls the methods with dynamic messages.
+type caller struct {
+	conn    *grpc.ClientConn
+	rc      *grpcreflect.Client
+	verbose bool
+	out     io.Writer
+
+	marshaler *jsonpb.Marshaler
+}
+
+// call calls the method args[0] with the request messages args[1:], or else
+// the JSON objects read from stdin.
+func (c *caller) call(ctx context.Context, args []string, stdin io.Reader) error {
+	if len(args) == 0 {
+		return fmt.Errorf("call needs a method, e.g. echo.Echo/UnaryEcho")
+	}
+	d, err := resolve(c.rc, args[0])
+	if err != nil {
+		return err
+	}
+	md, ok := d.(*desc.MethodDescriptor)
+	if !ok {
+		return fmt.Errorf("%s is a %s, not a method", d.GetFullyQualifiedName(), kind(d))
+	}
+
+	resolver := anyResolver{c.rc}
+	c.marshaler = &jsonpb.Marshaler{Indent: "  ", AnyResolver: resolver}
+	unmarshaler := &jsonpb.Unmarshaler{AnyResolver: resolver}
+
+	var inputs []json.RawMessage
+	if len(args) > 1 {
+		for _, a := range args[1:] {
+			inputs = append(inputs, json.RawMessage(a))
+		}
+	} else {
+		dec := json.NewDecoder(stdin)
+		for {
+			var in json.RawMessage
+			if err := dec.Decode(&in); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to read the request messages: %v", err)
+			}
+			inputs = append(inputs, in)
+		}
+	}
+	reqs := make([]proto.Message, len(inputs))
+	for i, in := range inputs {
+		req := dynamic.NewMessage(md.GetInputType())
+		if err := req.UnmarshalJSONPB(unmarshaler, in); err != nil {
+			return fmt.Errorf("invalid request message %d for %s: %v", i+1, md.GetInputType().GetFullyQualifiedName(), err)
+		}
+		reqs[i] = req
+	}
+	if !md.IsClientStreaming() {
+		switch len(reqs) {
+		case 0:
+			reqs = append(reqs, dynamic.NewMessage(md.GetInputType()))
+		case 1:
+		default:
+			return fmt.Errorf("%s takes a single request message, got %d", md.GetName(), len(reqs))
+		}
+	}
+
+	var header, trailer metadata.MD
+	stub := grpcdynamic.NewStub(c.conn)
+	switch {
+	case !md.IsClientStreaming() && !md.IsServerStreaming():
+		resp, err := stub.InvokeRpc(ctx, md, reqs[0], grpc.Header(&header), grpc.Trailer(&trailer))
+		c.printMetadata("header", header)
+		if err == nil {
+			err = c.print(resp)
+		}
+		c.printMetadata("trailer", trailer)
+		return err
+
+	case !md.IsClientStreaming():
+		stream, err := stub.InvokeRpcServerStream(ctx, md, reqs[0])
+		if err != nil {
+			return err
+		}
+		return c.receive(stream.Header, stream.Trailer, stream.RecvMsg)
+
+	case !md.IsServerStreaming():
+		stream, err := stub.InvokeRpcClientStream(ctx, md)
+		if err != nil {
+			return err
+		}
+		for _, req := range reqs {
+			if err := stream.SendMsg(req); err != nil {
+				break // The error of the stream is returned by CloseAndReceive
+			}
+		}
+		resp, err := stream.CloseAndReceive()
+		header, _ = stream.Header()
+		c.printMetadata("header", header)
+		if err == nil {
+			err = c.print(resp)
+		}
+		c.printMetadata("trailer", stream.Trailer())
+		return err
+
+	default:
+		stream, err := stub.InvokeRpcBidiStream(ctx, md)
+		if err != nil {
+			return err
+		}
+		// Send while receiving, the server may respond before the last request
+		go func() {
+			for _, req := range reqs {
+				if err := stream.SendMsg(req); err != nil {
+					return
+				}
+			}
+			stream.CloseSend()
+		}()
+		return c.receive(stream.Header, stream.Trailer, stream.RecvMsg)
+	}
+}
+
+// receive prints the responses of a stream until it ends.
+func (c *caller) receive(header func() (metadata.MD, error), trailer func() metadata.MD, recv func() (proto.Message, error)) error {
+	md, err := header()
+	if err != nil {
+		return err
+	}
+	c.printMetadata("header", md)
+	for {
+		resp, err := recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.print(resp); err != nil {
+			return err
+		}
+	}
+	c.printMetadata("trailer", trailer())
+	return nil
+}
+
+// print prints a response message as JSON.
+func (c *caller) print(m proto.Message) error {
+	s, err := c.marshaler.MarshalToString(m)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, s)
+	return nil
+}
+
+// printMetadata prints the response header or trailer to stderr with -v.
+func (c *caller) printMetadata(name string, md metadata.MD) {
+	if !c.verbose || len(md) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", name, k, v)
+		}
+	}
+}
+
+// formatError formats an error, with the code and details of a status.
+func formatError(err error, resolver jsonpb.AnyResolver) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return "Error: " + err.Error()
+	}
+	s := fmt.Sprintf("ERROR:\n  Code: %v\n  Message: %s", st.Code(), st.Message())
+	m := &jsonpb.Marshaler{Indent: "  ", AnyResolver: resolver}
+	for _, d := range st.Proto().GetDetails() {
+		detail, err := m.MarshalToString(d)
+		if err != nil {
+			detail = d.GetTypeUrl()
+		}
+		s += "\n  Details: " + strings.Replace(detail, "\n", "\n  ", -1)
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+// Package healthcheck registers the standard gRPC health service
+// (grpc.health.v1.Health) in the servers, so that orchestrators and load
+// balancers can probe them with Check or Watch instead of TCP connects, and
+// serves the /healthz and /readyz HTTP probes of the gateways.
+package healthcheck
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ServiceName is the name of the health service.
+const ServiceName = "grpc.health.v1.Health"
+
+// Register registers a health server in s, and reports SERVING for the whole
+// server (the empty service name) and for every service already registered in
+// s, so it must be called after them.
+func Register(s *grpc.Server) *health.Server {
+	h := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		h.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(s, h)
+	return h
+}
+
+// IsHealthMethod reports whether fullMethod is a method of the health service,
+// which the probes call without credentials.
+func IsHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+ServiceName+"/")
+}
+
+// Check returns the serving status of service, e.g. "user.UserService" or ""
+// for the whole server, as reported by the health service of conn.
+func Check(ctx context.Context, conn *grpc.ClientConn, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+	return resp.GetStatus(), nil
+}
@@ -0,0 +1,42 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// checkTimeout bounds the health check made by ReadyHandler.
+const checkTimeout = time.Second
+
+// LiveHandler returns the handler of the liveness probe (/healthz), which
+// succeeds as long as the process serves HTTP requests.
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// ReadyHandler returns the handler of the readiness probe (/readyz), which
+// succeeds only while service reports SERVING through the health service of
+// conn, the upstream gRPC server of a gateway. It fails with 503 Service
+// Unavailable otherwise, e.g. during the shutdown of the gRPC server.
+func ReadyHandler(conn *grpc.ClientConn, service string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+		s, err := Check(ctx, conn, service)
+		switch {
+		case err != nil:
+			http.Error(w, fmt.Sprintf("health check of %q failed: %v", service, err), http.StatusServiceUnavailable)
+		case s != healthpb.HealthCheckResponse_SERVING:
+			http.Error(w, fmt.Sprintf("%q is %v", service, s), http.StatusServiceUnavailable)
+		default:
+			fmt.Fprintln(w, "ok")
+		}
+	})
+}
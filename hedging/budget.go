@@ -0,0 +1,46 @@
+package hedging
+
+import "sync"
+
+// Budget is a token bucket that bounds the extra load caused by hedging: every
+// call puts ratio tokens in the bucket, up to burst tokens, and every hedged
+// attempt takes one. With a ratio of 0.1, at most 10% more requests are sent
+// to the servers, however slow they get.
+type Budget struct {
+	mu     sync.Mutex
+	tokens float64
+	ratio  float64
+	burst  float64
+}
+
+// NewBudget returns a full Budget, e.g. NewBudget(0.1, 10).
+func NewBudget(ratio, burst float64) *Budget {
+	return &Budget{tokens: burst, ratio: ratio, burst: burst}
+}
+
+// deposit is called for every hedged call.
+func (b *Budget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// take reports whether a hedged attempt may be sent, and takes its token.
+func (b *Budget) take() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,139 @@
+package hedging
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// AttemptKey is the metadata key that numbers the hedged attempts of a call,
+// starting at 2, so that servers can tell them apart.
+const AttemptKey = "hedge-attempt"
+
+// result is the outcome of an attempt.
+type result struct {
+	reply proto.Message
+	err   error
+
+	// The targets of the Header, Trailer and Peer call options of the attempt
+	header, trailer metadata.MD
+	peer            peer.Peer
+}
+
+// callOptions returns callOpts with the Header, Trailer and Peer options
+// writing to res instead of the variables of the caller, which the concurrent
+// attempts would otherwise write at the same time.
+func (res *result) callOptions(callOpts []grpc.CallOption) []grpc.CallOption {
+	opts := make([]grpc.CallOption, len(callOpts))
+	for i, opt := range callOpts {
+		switch opt.(type) {
+		case grpc.HeaderCallOption:
+			opt = grpc.Header(&res.header)
+		case grpc.TrailerCallOption:
+			opt = grpc.Trailer(&res.trailer)
+		case grpc.PeerCallOption:
+			opt = grpc.Peer(&res.peer)
+		}
+		opts[i] = opt
+	}
+	return opts
+}
+
+// copyTo sets the variables of the Header, Trailer and Peer options of
+// callOpts to those of the attempt, once it is the one returned to the caller.
+func (res *result) copyTo(callOpts []grpc.CallOption) {
+	for _, opt := range callOpts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			*o.HeaderAddr = res.header
+		case grpc.TrailerCallOption:
+			*o.TrailerAddr = res.trailer
+		case grpc.PeerCallOption:
+			*o.PeerAddr = res.peer
+		}
+	}
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that hedges
+// the calls of the methods set with WithMethods. The attempts call invoker
+// concurrently, so it must be the last interceptor of a chain: the chains of
+// grpc_middleware v1.0.0 are not safe for concurrent invocations, unlike those
+// of grpc.WithChainUnaryInterceptor.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	w := newWindow()
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		out, ok := reply.(proto.Message)
+		if !o.methods[method] || o.maxAttempts < 2 || !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		o.budget.deposit()
+
+		// Canceling ctx when the call returns cancels the attempts that lost
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		start := time.Now()
+		results := make(chan *result, o.maxAttempts) // never blocks the attempts that lost
+		attempt := func(n int) {
+			actx := ctx
+			if n > 1 {
+				actx = metadata.AppendToOutgoingContext(ctx, AttemptKey, strconv.Itoa(n))
+			}
+			// Every attempt decodes its own reply and metadata, since they may run concurrently
+			res := &result{reply: proto.Clone(out)}
+			res.reply.Reset()
+			res.err = invoker(actx, method, req, res.reply, cc, res.callOptions(callOpts)...)
+			results <- res
+		}
+
+		delay := o.delay
+		if o.percentile > 0 {
+			if d, ok := w.percentile(method, o.percentile); ok {
+				delay = d
+			}
+		}
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		sent, pending := 1, 1
+		go attempt(1)
+		for {
+			select {
+			case <-timer.C:
+				if sent < o.maxAttempts && o.budget.take() {
+					sent++
+					pending++
+					go attempt(sent)
+					timer.Reset(delay)
+				}
+			case res := <-results:
+				pending--
+				if res.err == nil {
+					w.record(method, time.Since(start))
+					out.Reset()
+					proto.Merge(out, res.reply)
+					res.copyTo(callOpts)
+					return nil
+				}
+				if !o.fatal(status.Code(res.err)) && sent < o.maxAttempts && o.budget.take() {
+					// The server did not process the request, no need to wait for the timer
+					sent++
+					pending++
+					go attempt(sent)
+					continue
+				}
+				if pending == 0 || o.fatal(status.Code(res.err)) {
+					res.copyTo(callOpts)
+					return res.err
+				}
+			}
+		}
+	}
+}
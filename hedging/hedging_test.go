@@ -0,0 +1,96 @@
+package hedging
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// slowFirstServer sends the number of the attempt in the headers at once, and
+// only answers the first attempt after delay.
+type slowFirstServer struct {
+	echoserver.Server
+	delay time.Duration
+}
+
+func (s *slowFirstServer) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	attempt := "1"
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[AttemptKey]) > 0 {
+		attempt = md[AttemptKey][0]
+	}
+	if err := grpc.SendHeader(ctx, metadata.Pairs("attempt", attempt)); err != nil {
+		return nil, err
+	}
+	if attempt == "1" {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// dial starts srv over an in-memory listener and returns a client hedging
+// with opts, and a function stopping both.
+func dial(t *testing.T, srv pb.EchoServer, opts ...Option) (pb.EchoClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	pb.RegisterEchoServer(s, srv)
+	go s.Serve(lis)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(opts...)),
+	)
+	if err != nil {
+		s.Stop()
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return pb.NewEchoClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+// Run with -race: both attempts are in flight with the Header and Peer options
+// of the caller, the first one only ending once the call returned.
+func TestHeaderOfTheWinningAttempt(t *testing.T) {
+	c, stop := dial(t, &slowFirstServer{delay: 500 * time.Millisecond},
+		WithMethods("/echo.Echo/UnaryEcho"),
+		WithDelay(20*time.Millisecond),
+		WithBudget(NewBudget(1, 10)),
+	)
+	defer stop()
+
+	var header metadata.MD
+	var p peer.Peer
+	res, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"}, grpc.Header(&header), grpc.Peer(&p))
+	if err != nil {
+		t.Fatalf("UnaryEcho() failed: %v", err)
+	}
+	if res.GetMessage() != "hello" {
+		t.Errorf("UnaryEcho() = %q, want %q", res.GetMessage(), "hello")
+	}
+	if got := header["attempt"]; len(got) != 1 || got[0] != "2" {
+		t.Errorf("header attempt = %q, want the [2] of the hedged attempt", got)
+	}
+	if p.Addr == nil {
+		t.Errorf("the peer of the winning attempt was not set")
+	}
+
+	// The losing attempt ends after the call, and must not overwrite them
+	time.Sleep(100 * time.Millisecond)
+	if got := header["attempt"]; len(got) != 1 || got[0] != "2" {
+		t.Errorf("header attempt after the losing attempt ended = %q, want [2]", got)
+	}
+}
@@ -0,0 +1,62 @@
+package hedging
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// window keeps the latest latencies of successful calls, per method.
+type window struct {
+	mu      sync.Mutex
+	samples map[string]*samples
+}
+
+// samples is a ring buffer of latencies.
+type samples struct {
+	d    [100]time.Duration
+	n    int // number of samples recorded, may exceed len(d)
+	next int
+}
+
+func newWindow() *window {
+	return &window{samples: make(map[string]*samples)}
+}
+
+func (w *window) record(method string, d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s, ok := w.samples[method]
+	if !ok {
+		s = &samples{}
+		w.samples[method] = s
+	}
+	s.d[s.next] = d
+	s.next = (s.next + 1) % len(s.d)
+	s.n++
+}
+
+// percentile returns the p-th percentile of the latencies of method, or false
+// while too few calls have been recorded to tell.
+func (w *window) percentile(method string, p float64) (time.Duration, bool) {
+	w.mu.Lock()
+	s, ok := w.samples[method]
+	if !ok || s.n < 20 {
+		w.mu.Unlock()
+		return 0, false
+	}
+	n := s.n
+	if n > len(s.d) {
+		n = len(s.d)
+	}
+	d := make([]time.Duration, n)
+	copy(d, s.d[:n])
+	w.mu.Unlock()
+
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	i := int(p * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return d[i], true
+}
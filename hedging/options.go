@@ -0,0 +1,103 @@
+// Package hedging provides a client interceptor that hedges idempotent unary
+// RPCs: when the first attempt is slow to answer, more attempts are sent to the
+// server, the first successful response is used and the other attempts are
+// canceled. This cuts the tail latency caused by a few slow requests, at the
+// price of some extra load, which is bounded by a budget.
+//
+// Only idempotent methods may be hedged, since the server may process the same
+// request several times, e.g. "/math.Math/Sum", "/greet.Greeter/SayHello" or
+// "/user.UserService/Get".
+package hedging
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+type options struct {
+	methods     map[string]bool
+	maxAttempts int
+	delay       time.Duration
+	percentile  float64
+	nonFatal    []codes.Code
+	budget      *Budget
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		methods:     make(map[string]bool),
+		maxAttempts: 2,
+		delay:       100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) fatal(c codes.Code) bool {
+	for _, nc := range o.nonFatal {
+		if nc == c {
+			return false
+		}
+	}
+	return true
+}
+
+// Option configures the hedging interceptor.
+type Option func(*options)
+
+// WithMethods sets the full names of the methods that are hedged, e.g.
+// "/math.Math/Sum". No method is hedged by default.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		for _, m := range methods {
+			o.methods[m] = true
+		}
+	}
+}
+
+// WithMaxAttempts sets the maximum number of attempts of a call, including the
+// first one (2 by default).
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithDelay sets how long to wait for a response before sending the next
+// attempt (100ms by default).
+func WithDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.delay = d
+	}
+}
+
+// WithPercentile waits for the p-th percentile (e.g. 0.95) of the latencies
+// observed for the method before sending the next attempt. The delay set by
+// WithDelay is used until enough calls have been observed.
+func WithPercentile(p float64) Option {
+	return func(o *options) {
+		o.percentile = p
+	}
+}
+
+// WithNonFatalCodes sets the status codes for which the next attempt is sent at
+// once, if the budget allows it, instead of failing the call. Such an error only
+// fails the call when no other attempt is pending. Any other error is fatal: it
+// fails the call at once and cancels the pending attempts. By default every
+// error is fatal.
+func WithNonFatalCodes(c ...codes.Code) Option {
+	return func(o *options) {
+		o.nonFatal = c
+	}
+}
+
+// WithBudget shares a hedging budget between calls. No hedged attempt is sent
+// without a budget, which must be set.
+func WithBudget(b *Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
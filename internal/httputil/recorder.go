@@ -0,0 +1,29 @@
+// Package httputil holds the HTTP helpers shared by the metrics and tracing
+// handlers of the gateway.
+package httputil
+
+import "net/http"
+
+// StatusRecorder wraps http.ResponseWriter to record the status code of the response.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Code int
+}
+
+// NewStatusRecorder returns a StatusRecorder of w, with the 200 status code
+// written by default.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, Code: http.StatusOK}
+}
+
+func (r *StatusRecorder) WriteHeader(code int) {
+	r.Code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets the streaming responses of the gateway through.
+func (r *StatusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
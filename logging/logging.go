@@ -0,0 +1,384 @@
+// Package logging provides interceptors that log one JSON line per call, with
+// its method, peer, principal, final status, latency, the messages and bytes
+// exchanged, and a request ID that correlates the lines of a request across
+// services.
+//
+// The request ID is read from the x-request-id metadata of the incoming calls,
+// or generated if missing, and returned to the client as a response header.
+// The outgoing calls made while handling a call carry its request ID.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/callstats"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey is the metadata key of the request ID.
+const RequestIDKey = "x-request-id"
+
+// Level is the severity of a log line.
+type Level int32
+
+// Levels, the lines below the level of a Logger are dropped.
+// Successful calls are logged at Info, client errors at Warn and server errors at Error.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses the name of a level, e.g. "info".
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// levelOf returns the level of a call that finished with code.
+func levelOf(code codes.Code) Level {
+	switch code {
+	case codes.OK:
+		return Info
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange, codes.DeadlineExceeded:
+		return Warn
+	default:
+		return Error
+	}
+}
+
+// Logger writes the log lines of the calls.
+type Logger struct {
+	level    int32  // Level, accessed atomically so that it can be changed at run time
+	sampling uint64 // math.Float64bits of the fraction of the successful calls logged, accessed atomically
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// Option configures a Logger.
+type Option func(*Logger)
+
+// WithLevel sets the minimum level of the lines written (Info by default).
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.SetLevel(level)
+	}
+}
+
+// WithSampling only logs a fraction (between 0 and 1) of the successful calls,
+// to keep the volume of logs down on busy servers. Failed calls are always
+// logged. All calls are logged by default.
+func WithSampling(fraction float64) Option {
+	return func(l *Logger) {
+		l.SetSampling(fraction)
+	}
+}
+
+// New returns a Logger that writes to out.
+func New(out io.Writer, opts ...Option) *Logger {
+	l := &Logger{out: out}
+	l.SetLevel(Info)
+	l.SetSampling(1)
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Default is the Logger used by the package-level interceptors, which writes to
+// the standard error.
+var Default = New(os.Stderr)
+
+// SetLevel sets the minimum level of the lines written.
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+// Level returns the minimum level of the lines written.
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(&l.level))
+}
+
+// SetSampling sets the fraction of the successful calls logged.
+func (l *Logger) SetSampling(fraction float64) {
+	atomic.StoreUint64(&l.sampling, math.Float64bits(fraction))
+}
+
+func (l *Logger) sampled() bool {
+	fraction := math.Float64frombits(atomic.LoadUint64(&l.sampling))
+	return fraction >= 1 || mathrand.Float64() < fraction
+}
+
+// line is a log line.
+type line struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Msg       string `json:"msg"`
+	Side      string `json:"side"` // "server" or "client"
+	Method    string `json:"method"`
+	Peer      string `json:"peer,omitempty"`
+	Principal string `json:"principal,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	*result
+}
+
+// result is the outcome of a finished call.
+type result struct {
+	Code            string  `json:"code"`
+	LatencyMS       float64 `json:"latency_ms"`
+	MsgsSent        int64   `json:"msgs_sent"`
+	MsgsReceived    int64   `json:"msgs_received"`
+	BytesSent       int64   `json:"bytes_sent"`
+	BytesReceived   int64   `json:"bytes_received"`
+	FirstSentMS     float64 `json:"first_sent_ms,omitempty"`
+	FirstReceivedMS float64 `json:"first_received_ms,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
+
+func (l *Logger) write(level Level, ln line) {
+	if level < l.Level() {
+		return
+	}
+	ln.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	ln.Level = level.String()
+	data, err := json.Marshal(ln)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(data, '\n'))
+}
+
+// started logs the start of a call at Debug level.
+func (l *Logger) started(side, method string, c *call) {
+	c.mu.Lock()
+	ln := line{Msg: "started call", Side: side, Method: method, Peer: c.peer, Principal: c.principal, RequestID: c.requestID}
+	c.mu.Unlock()
+	l.write(Debug, ln)
+}
+
+// finished logs the end of a call.
+func (l *Logger) finished(side string, c *call, s callstats.Stats) {
+	code := status.Code(s.Err)
+	level := levelOf(code)
+	if level == Info && !l.sampled() {
+		return
+	}
+	c.mu.Lock()
+	ln := line{Msg: "finished call", Side: side, Method: s.Method, Peer: c.peer, Principal: c.principal, RequestID: c.requestID}
+	c.mu.Unlock()
+	ln.result = &result{
+		Code:            code.String(),
+		LatencyMS:       ms(s.Duration),
+		MsgsSent:        s.MsgsSent,
+		MsgsReceived:    s.MsgsReceived,
+		BytesSent:       s.BytesSent,
+		BytesReceived:   s.BytesReceived,
+		FirstSentMS:     ms(s.FirstSent),
+		FirstReceivedMS: ms(s.FirstReceived),
+	}
+	if s.Err != nil {
+		ln.Error = status.Convert(s.Err).Message()
+	}
+	l.write(level, ln)
+}
+
+// call holds the fields of a call that are known once it is handled.
+type call struct {
+	mu        sync.Mutex
+	peer      string
+	principal string
+	requestID string
+}
+
+type callKey struct{}
+
+// RequestID returns the request ID of the call handled with ctx, or "".
+func RequestID(ctx context.Context) string {
+	if c, ok := ctx.Value(callKey{}).(*call); ok {
+		return c.requestID
+	}
+	return ""
+}
+
+// SetPrincipal sets the principal logged for the call handled with ctx, e.g.
+// the user authenticated by an interceptor that runs after the logging one.
+func SetPrincipal(ctx context.Context, principal string) {
+	if c, ok := ctx.Value(callKey{}).(*call); ok {
+		c.mu.Lock()
+		c.principal = principal
+		c.mu.Unlock()
+	}
+}
+
+// newRequestID returns a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// newServerCall returns the fields of a call handled with ctx, and ctx with them.
+func newServerCall(ctx context.Context) (context.Context, *call) {
+	c := &call{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDKey); len(ids) > 0 && ids[0] != "" {
+			c.requestID = ids[0]
+		}
+	}
+	if c.requestID == "" {
+		c.requestID = newRequestID()
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		c.peer = p.Addr.String()
+		// The common name of the client certificate, with mutual TLS
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+			c.principal = tlsInfo.State.PeerCertificates[0].Subject.CommonName
+		}
+	}
+	return context.WithValue(ctx, callKey{}, c), c
+}
+
+// newClientCall returns the fields of a call made to cc with ctx, and ctx with
+// the request ID in its outgoing metadata.
+func newClientCall(ctx context.Context, cc *grpc.ClientConn) (context.Context, *call) {
+	c := &call{peer: cc.Target()}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if ids := md.Get(RequestIDKey); len(ids) > 0 && ids[0] != "" {
+		c.requestID = ids[0]
+		return ctx, c
+	}
+	// The request ID of the call being handled, if any
+	c.requestID = RequestID(ctx)
+	if c.requestID == "" {
+		c.requestID = newRequestID()
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, c.requestID), c
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that logs the calls.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, c := newServerCall(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, c.requestID))
+		l.started("server", info.FullMethod, c)
+		m, err := handler(ctx, req)
+		l.finished("server", c, callstats.UnaryServer(info.FullMethod, start, req, m, err))
+		return m, err
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that logs the streams.
+func (l *Logger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, c := newServerCall(ss.Context())
+		ss.SetHeader(metadata.Pairs(RequestIDKey, c.requestID))
+		l.started("server", info.FullMethod, c)
+		s := callstats.NewServerStream(ss, info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: s, ctx: ctx})
+		l.finished("server", c, s.Finish(err))
+		return err
+	}
+}
+
+// serverStream wraps grpc.ServerStream to replace its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that logs the calls.
+func (l *Logger) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, c := newClientCall(ctx, cc)
+		l.started("client", method, c)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		l.finished("client", c, callstats.UnaryClient(method, start, req, reply, err))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that logs
+// the streams once they are over.
+func (l *Logger) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, c := newClientCall(ctx, cc)
+		l.started("client", method, c)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			l.finished("client", c, callstats.Stats{Method: method, Start: start, Duration: time.Since(start), Err: err})
+			return nil, err
+		}
+		return callstats.NewClientStream(ctx, cs, desc, method, func(s callstats.Stats) {
+			l.finished("client", c, s)
+		}), nil
+	}
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that uses Default.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return Default.UnaryServerInterceptor()
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that uses Default.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return Default.StreamServerInterceptor()
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that uses Default.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return Default.UnaryClientInterceptor()
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that uses Default.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return Default.StreamClientInterceptor()
+}
@@ -3,13 +3,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"time"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"github.com/wangy8961/grpc-go-tutorial/hedging"
+	"github.com/wangy8961/grpc-go-tutorial/math/matherr"
 	pb "github.com/wangy8961/grpc-go-tutorial/math/mathpb"
+	"github.com/wangy8961/grpc-go-tutorial/metrics"
 	"google.golang.org/grpc"
 )
 
@@ -22,6 +29,11 @@ func unaryCall(c pb.MathClient) {
 	}
 	resp, err := c.Sum(context.Background(), req)
 	if err != nil {
+		// The errmap client interceptor turns the status back into a matherr error
+		var overflow *matherr.OverflowError
+		if errors.As(err, &overflow) {
+			log.Fatalf("the sum of %v does not fit in an int32", overflow.Operands)
+		}
 		log.Fatalf("failed to call Sum: %v", err)
 	}
 
@@ -118,11 +130,36 @@ func bidirectionalStreamingCall(c pb.MathClient) {
 }
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
+	cfg := bootstrap.DefaultConfig()
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
+	printMetrics := flag.Bool("metrics", false, "print the client metrics in the Prometheus format before exiting")
 	flag.Parse()
 
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	m := metrics.NewClient()
+	if *printMetrics {
+		defer m.WritePrometheus(os.Stdout)
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, grpc.WithInsecure()) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	opts := []grpc.DialOption{
+		// Map the errors of the Math service back to the matherr errors, and
+		// hedge the slow Sum calls, at most 10% more requests
+		grpc.WithChainUnaryInterceptor(
+			m.UnaryClientInterceptor(),
+			errmap.UnaryClientInterceptor(),
+			hedging.UnaryClientInterceptor(
+				hedging.WithMethods("/math.Math/Sum"),
+				hedging.WithBudget(hedging.NewBudget(0.1, 10)),
+			),
+		),
+		grpc.WithChainStreamInterceptor(m.StreamClientInterceptor(), errmap.StreamClientInterceptor()),
+	}
+	conn, err := bootstrap.Dial(&cfg.Client, opts...) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
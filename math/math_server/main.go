@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
+	"math"
 
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"github.com/wangy8961/grpc-go-tutorial/math/matherr"
 	pb "github.com/wangy8961/grpc-go-tutorial/math/mathpb"
-	"google.golang.org/grpc"
+	"github.com/wangy8961/grpc-go-tutorial/validate"
 )
 
 // server is used to implement mathpb.MathServer.
@@ -20,7 +23,11 @@ type server struct{}
 func (s *server) Sum(ctx context.Context, in *pb.SumRequest) (*pb.SumResponse, error) {
 	fmt.Printf("--- gRPC Unary RPC ---\n")
 	fmt.Printf("request received: %v\n", in)
-	return &pb.SumResponse{Result: in.FirstNum + in.SecondNum}, nil
+	sum := int64(in.FirstNum) + int64(in.SecondNum)
+	if sum > math.MaxInt32 || sum < math.MinInt32 {
+		return nil, &matherr.OverflowError{Op: "sum", Operands: []int64{int64(in.FirstNum), int64(in.SecondNum)}}
+	}
+	return &pb.SumResponse{Result: int32(sum)}, nil
 }
 
 // PrimeFactors implements mathpb.MathServer
@@ -28,7 +35,7 @@ func (s *server) PrimeFactors(in *pb.PrimeFactorsRequest, stream pb.Math_PrimeFa
 	fmt.Printf("--- gRPC Server-side Streaming RPC ---\n")
 	fmt.Printf("request received: %v\n", in)
 
-	num := in.Num
+	num := in.Num // at least 2, see the (validate.rules) of PrimeFactorsRequest
 	factor := int64(2)
 
 	for num > 1 {
@@ -56,16 +63,21 @@ func (s *server) Average(stream pb.Math_AverageServer) error {
 
 		if err == io.EOF {
 			fmt.Printf("Receiving client streaming data completed\n")
+			if count == 0 {
+				return matherr.ErrNoNumbers
+			}
 			average := float64(sum) / float64(count)
 			return stream.SendAndClose(&pb.AverageResponse{Result: average})
 		}
 
-		fmt.Printf("request received: %v\n", in)
-
 		if err != nil {
-			log.Fatalf("Error while receiving client streaming data: %v", err)
+			// e.g. a request rejected by the validation interceptor
+			fmt.Printf("Error while receiving client streaming data: %v\n", err)
+			return err
 		}
 
+		fmt.Printf("request received: %v\n", in)
+
 		sum += in.Num
 		count++
 	}
@@ -85,7 +97,8 @@ func (s *server) Maximum(stream pb.Math_MaximumServer) error {
 			return nil
 		}
 		if err != nil {
-			log.Fatalf("Error while receiving client streaming data: %v", err)
+			fmt.Printf("Error while receiving client streaming data: %v\n", err)
+			return err
 		}
 
 		num := in.Num
@@ -101,18 +114,34 @@ func (s *server) Maximum(stream pb.Math_MaximumServer) error {
 }
 
 func main() {
-	port := flag.Int("port", 50051, "the port to serve on")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Admin.Address = ":9090"
+	cfg.Server.Interceptors.Logging = true // Log one JSON line per call
+	cfg.Server.Interceptors.Metrics = true
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port)) // Specify the port we want to use to listen for client requests
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
-	fmt.Printf("server listening at %v\n", lis.Addr())
-
-	s := grpc.NewServer()                // Create an instance of the gRPC server
-	pb.RegisterMathServer(s, &server{})  // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil { // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	s, err := bootstrap.NewServer(cfg,
+		bootstrap.WithUnaryInterceptors(
+			// Map the matherr errors returned by handlers to gRPC status codes
+			errmap.UnaryServerInterceptor(),
+			// Reject requests that violate the (validate.rules) declared in math.proto
+			validate.UnaryServerInterceptor(),
+		),
+		bootstrap.WithStreamInterceptors(
+			errmap.StreamServerInterceptor(),
+			validate.StreamServerInterceptor(),
+		),
+	) // Create an instance of the gRPC server
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+	pb.RegisterMathServer(s.Server, &server{}) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {          // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
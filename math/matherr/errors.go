@@ -0,0 +1,60 @@
+// Package matherr declares the errors of the Math service.
+package matherr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"google.golang.org/grpc/codes"
+)
+
+// Domain is the ErrorInfo domain of the Math service errors.
+const Domain = "math.madmalls.com"
+
+var (
+	// ErrNoNumbers is returned by Average when the client sends no number.
+	ErrNoNumbers = errors.New("no numbers to average")
+)
+
+// OverflowError is returned when the result of an operation does not fit in its type.
+type OverflowError struct {
+	Op       string
+	Operands []int64
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("%s of %v overflows", e.Op, e.Operands)
+}
+
+func init() {
+	errmap.Register(ErrNoNumbers, errmap.Mapping{
+		Code:   codes.InvalidArgument,
+		Domain: Domain,
+		Reason: "NO_NUMBERS",
+	})
+	errmap.RegisterAs((*OverflowError)(nil), errmap.Mapping{
+		Code:   codes.OutOfRange,
+		Domain: Domain,
+		Reason: "INTEGER_OVERFLOW",
+		Encode: func(err error) map[string]string {
+			e := err.(*OverflowError)
+			operands := make([]string, len(e.Operands))
+			for i, n := range e.Operands {
+				operands[i] = strconv.FormatInt(n, 10)
+			}
+			return map[string]string{"op": e.Op, "operands": strings.Join(operands, ",")}
+		},
+		Decode: func(md map[string]string) error {
+			e := &OverflowError{Op: md["op"]}
+			for _, s := range strings.Split(md["operands"], ",") {
+				if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+					e.Operands = append(e.Operands, n)
+				}
+			}
+			return e
+		},
+	})
+}
@@ -7,6 +7,7 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	_ "github.com/wangy8961/grpc-go-tutorial/validate"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -26,6 +27,8 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 // The request message for Sum.
 type SumRequest struct {
+	// Not bounded: a sum that does not fit in an int32 is reported by the
+	// server as a matherr.OverflowError, OUT_OF_RANGE with its operands.
 	FirstNum             int32    `protobuf:"varint,1,opt,name=first_num,json=firstNum,proto3" json:"first_num,omitempty"`
 	SecondNum            int32    `protobuf:"varint,2,opt,name=second_num,json=secondNum,proto3" json:"second_num,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -114,6 +117,7 @@ func (m *SumResponse) GetResult() int32 {
 
 // The request message for PrimeFactors.
 type PrimeFactorsRequest struct {
+	// Only the numbers greater than 1 can be factored.
 	Num                  int64    `protobuf:"varint,1,opt,name=num,proto3" json:"num,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -366,27 +370,28 @@ func init() {
 func init() { proto.RegisterFile("math.proto", fileDescriptor_f139a3799a86a974) }
 
 var fileDescriptor_f139a3799a86a974 = []byte{
-	// 306 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0xcb, 0x4b, 0xf3, 0x40,
-	0x14, 0xc5, 0xbf, 0xf9, 0xd2, 0xe7, 0x55, 0xb4, 0x5e, 0xab, 0x68, 0x44, 0x90, 0x01, 0xb1, 0x82,
-	0x84, 0xa2, 0x3b, 0x71, 0xa3, 0x0b, 0xd1, 0x45, 0x8b, 0xa4, 0x3b, 0x37, 0x92, 0xd6, 0xd1, 0x06,
-	0x9c, 0x24, 0xce, 0x43, 0xfc, 0xd7, 0xdd, 0x49, 0x26, 0x99, 0x34, 0x29, 0x83, 0xbb, 0xc9, 0x3d,
-	0xe7, 0xfc, 0x86, 0x7b, 0x32, 0x00, 0x3c, 0x52, 0xcb, 0x20, 0x13, 0xa9, 0x4a, 0xb1, 0x95, 0x9f,
-	0xe9, 0x03, 0xc0, 0x4c, 0xf3, 0x90, 0x7d, 0x6a, 0x26, 0x15, 0x1e, 0x41, 0xff, 0x2d, 0x16, 0x52,
-	0xbd, 0x24, 0x9a, 0x1f, 0x90, 0x13, 0x32, 0x6a, 0x87, 0x3d, 0x33, 0x98, 0x6a, 0x8e, 0xc7, 0x00,
-	0x92, 0x2d, 0xd2, 0xe4, 0xd5, 0xa8, 0xff, 0x8d, 0xda, 0x2f, 0x26, 0x53, 0xcd, 0xe9, 0x29, 0x6c,
-	0x18, 0x92, 0xcc, 0xd2, 0x44, 0x32, 0xdc, 0x87, 0x8e, 0x60, 0x52, 0x7f, 0xa8, 0x92, 0x53, 0x7e,
-	0xd1, 0x33, 0xd8, 0x7d, 0x12, 0x31, 0x67, 0xf7, 0xd1, 0x42, 0xa5, 0x42, 0xda, 0x9b, 0x07, 0xe0,
-	0xd9, 0x3b, 0xbd, 0x30, 0x3f, 0xd2, 0x00, 0x86, 0x4d, 0xa3, 0x13, 0xec, 0x55, 0x60, 0x0a, 0x5b,
-	0xb7, 0x5f, 0x4c, 0x44, 0xef, 0xcc, 0xc1, 0x6c, 0x17, 0xcc, 0x73, 0xd8, 0xae, 0x3c, 0x4e, 0x1c,
-	0xa9, 0xe3, 0x26, 0xd1, 0x77, 0xcc, 0x57, 0xe5, 0x38, 0x71, 0x95, 0xe7, 0xef, 0xb5, 0x2f, 0x7f,
-	0x08, 0xb4, 0x26, 0x91, 0x5a, 0xe2, 0x05, 0x78, 0x33, 0xcd, 0x71, 0x10, 0x98, 0x5f, 0xb1, 0xea,
-	0xde, 0xdf, 0xa9, 0x4d, 0x0a, 0x18, 0xfd, 0x87, 0x8f, 0xb0, 0x59, 0x2f, 0x01, 0x0f, 0x0b, 0x93,
-	0xa3, 0x41, 0xdf, 0x77, 0x49, 0x16, 0x34, 0x26, 0x78, 0x0d, 0xdd, 0x72, 0x77, 0x1c, 0x16, 0xd6,
-	0x66, 0x5d, 0xfe, 0xde, 0xda, 0xd4, 0x66, 0x47, 0x04, 0x6f, 0xa0, 0x5b, 0x2e, 0x6a, 0xb3, 0xcd,
-	0x6e, 0x6c, 0x76, 0xad, 0x8d, 0x3c, 0x3b, 0x26, 0x77, 0xbd, 0xe7, 0x4e, 0xae, 0x66, 0xf3, 0x79,
-	0xc7, 0x3c, 0xbd, 0xab, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x18, 0x37, 0x9e, 0xce, 0x88, 0x02,
-	0x00, 0x00,
+	// 332 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0x4b, 0x4f, 0x32, 0x31,
+	0x14, 0xa5, 0xdf, 0xf0, 0xbc, 0x9f, 0x0f, 0xac, 0xa0, 0x58, 0x63, 0x34, 0x4d, 0x4c, 0x30, 0x31,
+	0x88, 0x9a, 0xb8, 0x30, 0x2e, 0x94, 0x85, 0xd1, 0x05, 0xc4, 0xc0, 0xce, 0x8d, 0x29, 0x50, 0x65,
+	0x12, 0xca, 0x60, 0x1f, 0xc4, 0xdf, 0xe8, 0xdf, 0x71, 0xe5, 0xce, 0xd0, 0x4e, 0x79, 0x65, 0xc2,
+	0xac, 0x3a, 0xe7, 0xdc, 0x73, 0x4e, 0x7a, 0x6e, 0x01, 0x04, 0xd3, 0x83, 0xda, 0x58, 0x46, 0x3a,
+	0xc2, 0xe9, 0xe9, 0x99, 0xec, 0x4f, 0xd8, 0x30, 0xec, 0x33, 0xcd, 0x2f, 0xfc, 0xc1, 0xd1, 0xf4,
+	0x09, 0xa0, 0x63, 0x44, 0x9b, 0x7f, 0x1a, 0xae, 0x34, 0x3e, 0x84, 0xc2, 0x7b, 0x28, 0x95, 0x7e,
+	0x1b, 0x19, 0x51, 0x41, 0x27, 0xa8, 0x9a, 0x69, 0xe7, 0x2d, 0xd0, 0x32, 0x02, 0x1f, 0x01, 0x28,
+	0xde, 0x8b, 0x46, 0x7d, 0xcb, 0xfe, 0xb3, 0x6c, 0xc1, 0x21, 0x2d, 0x23, 0xe8, 0x29, 0xfc, 0xb7,
+	0x4e, 0x6a, 0x1c, 0x8d, 0x14, 0xc7, 0x7b, 0x90, 0x95, 0x5c, 0x99, 0xa1, 0x8e, 0x7d, 0xe2, 0x3f,
+	0x7a, 0x03, 0xbb, 0x2f, 0x32, 0x14, 0xfc, 0x91, 0xf5, 0x74, 0x24, 0x95, 0x4f, 0x3e, 0x86, 0xc0,
+	0x67, 0x06, 0x8d, 0xcd, 0xef, 0x9f, 0x4a, 0xe1, 0x32, 0xe5, 0xbe, 0xfb, 0xf6, 0x94, 0xa1, 0x35,
+	0x28, 0x2d, 0xeb, 0x12, 0x73, 0x82, 0x59, 0x0e, 0x85, 0xad, 0x87, 0x09, 0x97, 0xec, 0x83, 0xfb,
+	0x88, 0xe2, 0x3c, 0x22, 0xe3, 0x3c, 0xcf, 0x60, 0x7b, 0x36, 0x93, 0x68, 0x87, 0x16, 0xed, 0x9a,
+	0xec, 0x2b, 0x14, 0x46, 0xac, 0xb5, 0x9b, 0xcd, 0xac, 0x6f, 0xe1, 0xea, 0x17, 0x41, 0xba, 0xc9,
+	0xf4, 0x00, 0x9f, 0x43, 0xd0, 0x31, 0x02, 0x17, 0x6b, 0x76, 0x65, 0xf3, 0x55, 0x90, 0x9d, 0x05,
+	0xc4, 0x99, 0xd1, 0x14, 0x7e, 0x86, 0x8d, 0xc5, 0x12, 0xf0, 0x81, 0x1b, 0x4a, 0x28, 0x94, 0x90,
+	0x24, 0xca, 0x1b, 0xd5, 0x11, 0xbe, 0x85, 0x5c, 0x7c, 0x77, 0x5c, 0x72, 0xa3, 0xcb, 0x75, 0x91,
+	0xf2, 0x0a, 0xea, 0xb5, 0x55, 0x84, 0xef, 0x20, 0x17, 0x5f, 0xd4, 0x6b, 0x97, 0xbb, 0x21, 0xe5,
+	0x15, 0x74, 0xae, 0xad, 0xa3, 0x46, 0xfe, 0x35, 0x3b, 0x65, 0xc7, 0xdd, 0x6e, 0xd6, 0xbe, 0xc1,
+	0xeb, 0xbf, 0x01, 0x00, 0x61, 0x10, 0xbf, 0x36, 0xb0, 0x02, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
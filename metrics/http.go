@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/internal/httputil"
+)
+
+// HTTP collects the metrics of the requests handled by an HTTP server, such as
+// a gateway, so that REST latencies can be compared with the gRPC ones.
+type HTTP struct {
+	route func(*http.Request) string
+
+	mu       sync.Mutex
+	requests map[httpKey]int64
+	inFlight map[string]int64
+	latency  map[string]*Histogram
+}
+
+// httpKey is the key of the request counters.
+type httpKey struct {
+	route, method, code string
+}
+
+// NewHTTP returns empty HTTP metrics. route returns the label of the requests,
+// such as the route pattern "/v1/users/{username}": the raw paths would create
+// a time series per user.
+func NewHTTP(route func(*http.Request) string) *HTTP {
+	return &HTTP{
+		route:    route,
+		requests: make(map[httpKey]int64),
+		inFlight: make(map[string]int64),
+		latency:  make(map[string]*Histogram),
+	}
+}
+
+// Handler returns a handler that records the requests handled by next.
+func (h *HTTP) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := h.route(r)
+		start := time.Now()
+		h.mu.Lock()
+		h.inFlight[route]++
+		h.mu.Unlock()
+
+		rec := httputil.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.inFlight[route]--
+		h.requests[httpKey{route, r.Method, strconv.Itoa(rec.Code)}]++
+		hist, ok := h.latency[route]
+		if !ok {
+			hist = &Histogram{}
+			h.latency[route] = hist
+		}
+		hist.observe(time.Since(start))
+	})
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (h *HTTP) WritePrometheus(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]httpKey, 0, len(h.requests))
+	for k := range h.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+	family(w, "http_requests_total", "counter", "Total number of HTTP requests completed.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "http_requests_total%s %d\n", labels("route", k.route, "method", k.method, "code", k.code), h.requests[k])
+	}
+
+	routes := make([]string, 0, len(h.inFlight))
+	for route := range h.inFlight {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	family(w, "http_requests_in_flight", "gauge", "Number of HTTP requests in flight.")
+	for _, route := range routes {
+		fmt.Fprintf(w, "http_requests_in_flight%s %d\n", labels("route", route), h.inFlight[route])
+	}
+	family(w, "http_request_duration_seconds", "histogram", "Histogram of the latency of the HTTP requests.")
+	for _, route := range routes {
+		if hist, ok := h.latency[route]; ok {
+			histogram(w, "http_request_duration_seconds", *hist, "route", route)
+		}
+	}
+}
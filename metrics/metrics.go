@@ -0,0 +1,215 @@
+// Package metrics provides interceptors that collect the metrics of the calls
+// of servers and clients: number of calls started and handled by status code,
+// calls in flight, messages and bytes exchanged, and latency histograms, per
+// method. The metrics are served in the Prometheus text exposition format,
+// along with the HTTP metrics of the gateways.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/callstats"
+	"google.golang.org/grpc"
+)
+
+// Buckets are the upper bounds of the latency histogram buckets, in seconds.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Method holds the metrics of a method.
+type Method struct {
+	Started       int64
+	InFlight      int64
+	Handled       map[string]int64 // number of finished calls, by status code
+	MsgsSent      int64
+	MsgsReceived  int64
+	BytesSent     int64
+	BytesReceived int64
+	Panics        int64 // number of calls that panicked, recorded by the recovery interceptors
+	Latency       Histogram
+}
+
+// Histogram is a latency distribution.
+type Histogram struct {
+	Counts []int64 // number of calls that took at most Buckets[i], cumulative
+	Count  int64
+	Sum    float64 // in seconds
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]int64, len(Buckets))
+	}
+	s := d.Seconds()
+	for i, b := range Buckets {
+		if s <= b {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += s
+}
+
+// Metrics collects the metrics of the calls of a server or a client.
+type Metrics struct {
+	prefix string // of the metric names, "grpc_server" or "grpc_client"
+
+	mu      sync.Mutex
+	methods map[string]*Method
+}
+
+// NewServer returns empty Metrics for the calls handled by a server.
+func NewServer() *Metrics {
+	return &Metrics{prefix: "grpc_server", methods: make(map[string]*Method)}
+}
+
+// NewClient returns empty Metrics for the calls made by a client.
+func NewClient() *Metrics {
+	return &Metrics{prefix: "grpc_client", methods: make(map[string]*Method)}
+}
+
+// method returns the metrics of name. m.mu must be held.
+func (m *Metrics) method(name string) *Method {
+	mm, ok := m.methods[name]
+	if !ok {
+		mm = &Method{Handled: make(map[string]int64)}
+		m.methods[name] = mm
+	}
+	return mm
+}
+
+// start records the start of a call of method.
+func (m *Metrics) start(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mm := m.method(method)
+	mm.Started++
+	mm.InFlight++
+}
+
+// finish records the end of a call started with start.
+func (m *Metrics) finish(s callstats.Stats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mm := m.method(s.Method)
+	mm.InFlight--
+	mm.Handled[s.Code()]++
+	mm.MsgsSent += s.MsgsSent
+	mm.MsgsReceived += s.MsgsReceived
+	mm.BytesSent += s.BytesSent
+	mm.BytesReceived += s.BytesReceived
+	mm.Latency.observe(s.Duration)
+}
+
+// Panicked records that the handler of a call of method panicked. It is called
+// by the recovery interceptors, the call is also counted as Internal by finish.
+func (m *Metrics) Panicked(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.method(method).Panics++
+}
+
+// Snapshot returns a copy of the metrics, by full method name.
+func (m *Metrics) Snapshot() map[string]Method {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]Method, len(m.methods))
+	for name, mm := range m.methods {
+		c := *mm
+		c.Handled = make(map[string]int64, len(mm.Handled))
+		for code, n := range mm.Handled {
+			c.Handled[code] = n
+		}
+		c.Latency.Counts = append([]int64(nil), mm.Latency.Counts...)
+		snapshot[name] = c
+	}
+	return snapshot
+}
+
+// sortedMethods returns the names of the methods of a snapshot, sorted.
+func sortedMethods(snapshot map[string]Method) []string {
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitMethod splits a full method name, e.g. "/echo.Echo/UnaryEcho", into its
+// service and method names.
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+// Fprint prints a summary of the metrics to w, one line per method.
+func (m *Metrics) Fprint(w io.Writer) {
+	snapshot := m.Snapshot()
+	for _, name := range sortedMethods(snapshot) {
+		mm := snapshot[name]
+		avg := time.Duration(0)
+		if mm.Latency.Count > 0 {
+			avg = time.Duration(mm.Latency.Sum / float64(mm.Latency.Count) * float64(time.Second))
+		}
+		fmt.Fprintf(w, "%s: handled=%v in-flight=%d avg-latency=%v sent=%d msgs/%d bytes received=%d msgs/%d bytes\n",
+			name, mm.Handled, mm.InFlight, avg, mm.MsgsSent, mm.BytesSent, mm.MsgsReceived, mm.BytesReceived)
+	}
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that records the calls.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		m.start(info.FullMethod)
+		resp, err := handler(ctx, req)
+		m.finish(callstats.UnaryServer(info.FullMethod, start, req, resp, err))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that records the streams.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		m.start(info.FullMethod)
+		s := callstats.NewServerStream(ss, info.FullMethod)
+		err := handler(srv, s)
+		m.finish(s.Finish(err))
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that records the calls.
+func (m *Metrics) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		m.start(method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		m.finish(callstats.UnaryClient(method, start, req, reply, err))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that
+// records the streams. The streams stay in flight until they are read to the
+// end, or their context is done.
+func (m *Metrics) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		m.start(method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			m.finish(callstats.Stats{Method: method, Start: start, Duration: time.Since(start), Err: err})
+			return nil, err
+		}
+		return callstats.NewClientStream(ctx, cs, desc, method, m.finish), nil
+	}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Collector writes metrics in the Prometheus text exposition format.
+type Collector interface {
+	WritePrometheus(w io.Writer)
+}
+
+// Handler returns an HTTP handler that serves the metrics of the collectors in
+// the Prometheus text exposition format, e.g. on "/metrics" of an admin port.
+func Handler(collectors ...Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range collectors {
+			c.WritePrometheus(w)
+		}
+	})
+}
+
+// labels formats label pairs, e.g. labels("grpc_code", "OK") is `{grpc_code="OK"}`.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(escape(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// family writes the HELP and TYPE lines of a metric.
+func family(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// histogram writes the samples of a histogram with its labels.
+func histogram(w io.Writer, name string, h Histogram, pairs ...string) {
+	for i, b := range Buckets {
+		var n int64
+		if h.Counts != nil {
+			n = h.Counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(append(pairs, "le", formatFloat(b))...), n)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(append(pairs, "le", "+Inf")...), h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels(pairs...), formatFloat(h.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels(pairs...), h.Count)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	snapshot := m.Snapshot()
+	names := sortedMethods(snapshot)
+	counter := func(suffix, help string, value func(Method) int64) {
+		name := m.prefix + "_" + suffix
+		family(w, name, "counter", help)
+		for _, fullMethod := range names {
+			service, method := splitMethod(fullMethod)
+			fmt.Fprintf(w, "%s%s %d\n", name, labels("grpc_service", service, "grpc_method", method), value(snapshot[fullMethod]))
+		}
+	}
+
+	counter("started_total", "Total number of RPCs started.", func(mm Method) int64 { return mm.Started })
+
+	name := m.prefix + "_handled_total"
+	family(w, name, "counter", "Total number of RPCs completed, regardless of success or failure.")
+	for _, fullMethod := range names {
+		service, method := splitMethod(fullMethod)
+		handled := snapshot[fullMethod].Handled
+		codes := make([]string, 0, len(handled))
+		for code := range handled {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "%s%s %d\n", name, labels("grpc_service", service, "grpc_method", method, "grpc_code", code), handled[code])
+		}
+	}
+
+	name = m.prefix + "_in_flight"
+	family(w, name, "gauge", "Number of RPCs in flight.")
+	for _, fullMethod := range names {
+		service, method := splitMethod(fullMethod)
+		fmt.Fprintf(w, "%s%s %d\n", name, labels("grpc_service", service, "grpc_method", method), snapshot[fullMethod].InFlight)
+	}
+
+	counter("msg_received_total", "Total number of stream messages received.", func(mm Method) int64 { return mm.MsgsReceived })
+	counter("msg_sent_total", "Total number of stream messages sent.", func(mm Method) int64 { return mm.MsgsSent })
+	counter("bytes_received_total", "Total size of the messages received, in bytes.", func(mm Method) int64 { return mm.BytesReceived })
+	counter("bytes_sent_total", "Total size of the messages sent, in bytes.", func(mm Method) int64 { return mm.BytesSent })
+	if m.prefix == "grpc_server" {
+		counter("panics_recovered_total", "Total number of RPCs whose handler panicked.", func(mm Method) int64 { return mm.Panics })
+	}
+
+	name = m.prefix + "_handling_seconds"
+	family(w, name, "histogram", "Histogram of the latency of the RPCs, until they are completed.")
+	for _, fullMethod := range names {
+		service, method := splitMethod(fullMethod)
+		histogram(w, name, snapshot[fullMethod].Latency, "grpc_service", service, "grpc_method", method)
+	}
+}
+
+// ListenAndServe serves the metrics of the collectors on "/metrics" of addr,
+// e.g. ":9090", usually an admin port separate from the one of the service.
+func ListenAndServe(addr string, collectors ...Collector) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(collectors...))
+	return http.ListenAndServe(addr, mux)
+}
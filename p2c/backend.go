@@ -0,0 +1,91 @@
+package p2c
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/status"
+)
+
+// unobserved is the cost of a backend with outstanding calls, but no latency
+// observed yet: it gets no more calls than its first ones until they are
+// answered, unless the others are even slower.
+const unobserved = float64(time.Second)
+
+// backend holds the statistics of an address, guarded by the mutex of the
+// pickerBuilder.
+type backend struct {
+	addr   string
+	sc     balancer.SubConn
+	weight float64
+
+	outstanding int
+	latency     float64   // in nanoseconds, the peak EWMA of the latencies
+	observed    time.Time // when latency was last updated
+
+	failures     int // consecutive
+	ejections    int // consecutive
+	ejectedUntil time.Time
+}
+
+// cost returns the load of b at now, the lower the better.
+func (b *backend) cost(now time.Time, decay time.Duration) float64 {
+	if b.latency == 0 {
+		if b.outstanding == 0 {
+			return 0
+		}
+		return unobserved * float64(b.outstanding) / b.weight
+	}
+	return b.decayed(now, decay) * float64(b.outstanding+1) / b.weight
+}
+
+// decayed returns the latency of b decayed towards 0 since it was last
+// observed: a backend getting no calls, because it was slow, is tried again
+// after a while.
+func (b *backend) decayed(now time.Time, decay time.Duration) float64 {
+	return b.latency * math.Exp(-float64(now.Sub(b.observed))/float64(decay))
+}
+
+// observe records the end of a call sent to b at start, failing with err, and
+// returns whether b failed too many times in a row and should be ejected.
+func (b *backend) observe(now, start time.Time, err error, o *options) bool {
+	if err != nil && o.failure(status.Code(err)) {
+		// A backend failing fast must not look fast: the latency of the
+		// failures is not recorded
+		b.failures++
+		return o.consecutive > 0 && b.failures >= o.consecutive
+	}
+	b.failures = 0
+	b.ejections = 0
+
+	rtt := float64(now.Sub(start))
+	if rtt > b.latency {
+		// Peak-sensitive: a backend slowing down is avoided at once, a
+		// backend speeding up is trusted gradually
+		b.latency = rtt
+	} else {
+		w := math.Exp(-float64(now.Sub(b.observed)) / float64(o.decay))
+		b.latency = b.latency*w + rtt*(1-w)
+	}
+	b.observed = now
+	return false
+}
+
+// ejected returns whether b is ejected at now.
+func (b *backend) ejected(now time.Time) bool {
+	return now.Before(b.ejectedUntil)
+}
+
+// eject ejects b for longer each consecutive time.
+func (b *backend) eject(now time.Time, o *options) {
+	b.ejections++
+	d := o.baseEjection * time.Duration(b.ejections)
+	if d > o.maxEjection {
+		d = o.maxEjection
+	}
+	log.Printf("p2c: ejecting %s for %v after %d consecutive failures", b.addr, d, b.failures)
+	b.ejectedUntil = now.Add(d)
+	b.failures = 0
+}
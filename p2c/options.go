@@ -0,0 +1,82 @@
+package p2c
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+type options struct {
+	decay             time.Duration
+	failureCodes      []codes.Code
+	consecutive       int
+	baseEjection      time.Duration
+	maxEjection       time.Duration
+	maxEjectedPercent int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		decay:             10 * time.Second,
+		failureCodes:      []codes.Code{codes.Unavailable, codes.Internal, codes.Unknown, codes.DataLoss},
+		consecutive:       5,
+		baseEjection:      time.Second,
+		maxEjection:       30 * time.Second,
+		maxEjectedPercent: 50,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) failure(c codes.Code) bool {
+	for _, fc := range o.failureCodes {
+		if fc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Option configures the p2c balancer.
+type Option func(*options)
+
+// WithDecay sets how fast the latency of a backend is forgotten: the weight of
+// a latency observed d ago is exp(-d/decay). 10s by default. A short decay
+// follows the changes of load quickly, a long one smooths the spikes.
+func WithDecay(decay time.Duration) Option {
+	return func(o *options) {
+		o.decay = decay
+	}
+}
+
+// WithFailureCodes sets the codes counted as failures of the backend rather
+// than of the call, UNAVAILABLE, INTERNAL, UNKNOWN and DATA_LOSS by default.
+func WithFailureCodes(codes ...codes.Code) Option {
+	return func(o *options) {
+		o.failureCodes = codes
+	}
+}
+
+// WithEjection ejects a backend after consecutive failures, for base the first
+// time, then base longer for each consecutive ejection up to max. By default a
+// backend is ejected after 5 failures, for 1s, up to 30s. 0 consecutive
+// failures disables the ejection.
+func WithEjection(consecutive int, base, max time.Duration) Option {
+	return func(o *options) {
+		o.consecutive = consecutive
+		o.baseEjection = base
+		o.maxEjection = max
+	}
+}
+
+// WithMaxEjectedPercent sets the percentage of the backends that may be
+// ejected at the same time, 50 by default. At least one backend is never
+// ejected, so that a failure shared by all of them, e.g. a bad release, does
+// not leave the client with no backend at all.
+func WithMaxEjectedPercent(percent int) Option {
+	return func(o *options) {
+		o.maxEjectedPercent = percent
+	}
+}
@@ -0,0 +1,199 @@
+// Package p2c provides a load balancing policy that sends each call to the
+// least loaded of two backends picked at random, the "power of two choices".
+// The load of a backend is its number of outstanding calls times its latency,
+// a peak-sensitive moving average of the latencies of its calls, divided by
+// its weight, e.g. set in the endpoints file of a file:// target. A backend
+// busy with a huge request answers slowly and keeps more calls outstanding,
+// so it gets fewer calls, where round_robin keeps sending it its share.
+//
+// Backends that keep failing, e.g. answering UNAVAILABLE while their health
+// check still says SERVING, are ejected for a while: they get no calls until
+// the ejection expires, then they are tried again.
+//
+// Importing the package registers the policy under the name "p2c":
+//
+//	import _ "github.com/wangy8961/grpc-go-tutorial/p2c"
+//
+//	conn, err := grpc.Dial(target, grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy": "p2c"}`))
+package p2c
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/discovery"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// Name is the name of the policy, in the service config.
+const Name = "p2c"
+
+func init() {
+	balancer.Register(NewBuilder(Name))
+}
+
+// NewBuilder returns the builder of a p2c balancer configured by opts, to
+// register under name.
+func NewBuilder(name string, opts ...Option) balancer.Builder {
+	return &builder{name: name, opts: newOptions(opts)}
+}
+
+type builder struct {
+	name string
+	opts *options
+}
+
+// Build builds a balancer connecting to every address and health checking
+// them. Each ClientConn has its own statistics of the backends.
+func (b *builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &pickerBuilder{opts: b.opts, backends: make(map[string]*backend)}
+	return &p2cBalancer{
+		Balancer: base.NewBalancerBuilderWithConfig(b.name, pb, base.Config{HealthCheck: true}).Build(cc, opts),
+		pb:       pb,
+	}
+}
+
+func (b *builder) Name() string {
+	return b.name
+}
+
+// p2cBalancer is the balancer of the base package, which only gives the ready
+// addresses to the pickerBuilder: it tells it the addresses of the resolver as
+// well, so that the backends are kept while they are resolved.
+type p2cBalancer struct {
+	balancer.Balancer // a balancer.V2Balancer
+	pb                *pickerBuilder
+}
+
+func (b *p2cBalancer) HandleResolvedAddrs(addrs []resolver.Address, err error) {
+	if err == nil {
+		b.pb.resolved(addrs)
+	}
+	b.Balancer.HandleResolvedAddrs(addrs, err)
+}
+
+func (b *p2cBalancer) UpdateResolverState(s resolver.State) {
+	b.pb.resolved(s.Addresses)
+	b.Balancer.(balancer.V2Balancer).UpdateResolverState(s)
+}
+
+func (b *p2cBalancer) UpdateSubConnState(sc balancer.SubConn, s balancer.SubConnState) {
+	b.Balancer.(balancer.V2Balancer).UpdateSubConnState(sc, s)
+}
+
+// pickerBuilder builds the pickers of a ClientConn, every time the set of
+// ready backends changes. The backends are kept across the pickers, by
+// address, until the address is no longer resolved, so that their latency and
+// ejection survive a reconnection.
+type pickerBuilder struct {
+	opts *options
+
+	mu       sync.Mutex
+	backends map[string]*backend
+}
+
+func (pb *pickerBuilder) Build(readySCs map[resolver.Address]balancer.SubConn) balancer.Picker {
+	if len(readySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	p := &picker{pb: pb}
+	for addr, sc := range readySCs {
+		b, ok := pb.backends[addr.Addr]
+		if !ok {
+			b = &backend{addr: addr.Addr}
+			pb.backends[addr.Addr] = b
+		}
+		b.sc = sc
+		b.weight = float64(discovery.WeightOf(addr))
+		p.backends = append(p.backends, b)
+	}
+	return p
+}
+
+// resolved forgets the backends whose address is not in addrs any more.
+func (pb *pickerBuilder) resolved(addrs []resolver.Address) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	keep := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		keep[a.Addr] = true
+	}
+	for addr := range pb.backends {
+		if !keep[addr] {
+			delete(pb.backends, addr)
+		}
+	}
+}
+
+// picker picks the least loaded of two ready backends.
+type picker struct {
+	pb       *pickerBuilder
+	backends []*backend
+}
+
+func (p *picker) Pick(ctx context.Context, opts balancer.PickOptions) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	p.pb.mu.Lock()
+	defer p.pb.mu.Unlock()
+	now := time.Now()
+	candidates := make([]*backend, 0, len(p.backends))
+	for _, b := range p.backends {
+		if !b.ejected(now) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		// The backends left are all ejected, e.g. the one that was not went
+		// down: better try them than fail the call
+		candidates = p.backends
+	}
+
+	b := candidates[rand.Intn(len(candidates))]
+	if len(candidates) > 1 {
+		// Another one, at random among the others
+		i := rand.Intn(len(candidates) - 1)
+		if candidates[i] == b {
+			i = len(candidates) - 1
+		}
+		if other := candidates[i]; other.cost(now, p.pb.opts.decay) < b.cost(now, p.pb.opts.decay) {
+			b = other
+		}
+	}
+	b.outstanding++
+	sc := b.sc
+	return sc, func(info balancer.DoneInfo) {
+		p.done(b, now, info)
+	}, nil
+}
+
+// done records the end of a call sent to b at start.
+func (p *picker) done(b *backend, start time.Time, info balancer.DoneInfo) {
+	p.pb.mu.Lock()
+	defer p.pb.mu.Unlock()
+	now := time.Now()
+	b.outstanding--
+	if !b.observe(now, start, info.Err, p.pb.opts) {
+		return
+	}
+	// Too many failures in a row: eject b, unless too many are already
+	if n := p.ejected(now); (n+1)*100 > len(p.backends)*p.pb.opts.maxEjectedPercent || n+1 >= len(p.backends) {
+		return
+	}
+	b.eject(now, p.pb.opts)
+}
+
+// ejected returns the number of backends of p ejected at now.
+func (p *picker) ejected(now time.Time) int {
+	n := 0
+	for _, b := range p.backends {
+		if b.ejected(now) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,291 @@
+package p2c
+
+import (
+	"context"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/discovery"
+	echopb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSubConn is a balancer.SubConn that is never connected.
+type fakeSubConn struct {
+	balancer.SubConn
+	addr string
+}
+
+// readySCs returns the ready SubConns of addrs, as given to pickerBuilder.Build.
+func readySCs(addrs ...string) map[resolver.Address]balancer.SubConn {
+	m := make(map[resolver.Address]balancer.SubConn)
+	for _, a := range addrs {
+		m[resolver.Address{Addr: a}] = &fakeSubConn{addr: a}
+	}
+	return m
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func TestCost(t *testing.T) {
+	now := time.Now()
+	decay := 10 * time.Second
+
+	b := &backend{weight: 1}
+	if got := b.cost(now, decay); got != 0 {
+		t.Errorf("cost of an idle unobserved backend = %v, want 0", got)
+	}
+	b.outstanding = 2
+	if got := b.cost(now, decay); got != 2*unobserved {
+		t.Errorf("cost of an unobserved backend with 2 outstanding calls = %v, want %v", got, 2*unobserved)
+	}
+
+	b = &backend{weight: 1, outstanding: 1, latency: float64(10 * time.Millisecond), observed: now}
+	if got, want := b.cost(now, decay), float64(20*time.Millisecond); !approx(got, want) {
+		t.Errorf("cost = %v, want %v", got, want)
+	}
+	// The weight divides the cost
+	b.weight = 2
+	if got, want := b.cost(now, decay), float64(10*time.Millisecond); !approx(got, want) {
+		t.Errorf("cost with a weight of 2 = %v, want %v", got, want)
+	}
+	// The latency decays since it was observed
+	b.weight = 1
+	if got, want := b.cost(now.Add(decay), decay), float64(20*time.Millisecond)/math.E; !approx(got, want) {
+		t.Errorf("cost after the decay = %v, want %v", got, want)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	o := newOptions([]Option{WithEjection(3, time.Second, 10*time.Second)})
+	start := time.Now()
+	b := &backend{weight: 1}
+
+	if b.observe(start.Add(10*time.Millisecond), start, nil, o) {
+		t.Fatalf("observe() of a success asked for an ejection")
+	}
+	if got, want := b.latency, float64(10*time.Millisecond); got != want {
+		t.Errorf("latency = %v, want %v", time.Duration(got), time.Duration(want))
+	}
+
+	// Peak-sensitive: a slower call raises the latency at once
+	now := b.observed
+	b.observe(now.Add(50*time.Millisecond), now, nil, o)
+	if got, want := b.latency, float64(50*time.Millisecond); got != want {
+		t.Errorf("latency after a slow call = %v, want %v", time.Duration(got), time.Duration(want))
+	}
+
+	// A faster call lowers it gradually, by the weight of the time elapsed
+	now = b.observed.Add(o.decay)
+	b.observe(now, now.Add(-10*time.Millisecond), nil, o)
+	w := math.Exp(-1)
+	if got, want := b.latency, float64(50*time.Millisecond)*w+float64(10*time.Millisecond)*(1-w); !approx(got, want) {
+		t.Errorf("latency after a fast call = %v, want %v", time.Duration(got), time.Duration(want))
+	}
+
+	// The failures are counted, but their latency is not recorded
+	latency := b.latency
+	unavailable := status.Error(codes.Unavailable, "down")
+	for i := 1; i <= 3; i++ {
+		eject := b.observe(now, now, unavailable, o)
+		if eject != (i == 3) {
+			t.Errorf("observe() of failure %d = %v, want %v", i, eject, i == 3)
+		}
+	}
+	if b.latency != latency {
+		t.Errorf("latency after failures = %v, want %v", time.Duration(b.latency), time.Duration(latency))
+	}
+
+	// The errors of the calls are not failures of the backend, and reset the count
+	b.observe(now, now, status.Error(codes.InvalidArgument, "bad request"), o)
+	if b.failures != 0 {
+		t.Errorf("failures after an InvalidArgument error = %d, want 0", b.failures)
+	}
+}
+
+func TestEject(t *testing.T) {
+	o := newOptions([]Option{WithEjection(1, time.Second, 3*time.Second)})
+	now := time.Now()
+	b := &backend{addr: "a", weight: 1}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		b.eject(now, o)
+		if got := b.ejectedUntil.Sub(now); got != want {
+			t.Errorf("ejection %d lasts %v, want %v", b.ejections, got, want)
+		}
+		if !b.ejected(now) || b.ejected(now.Add(want)) {
+			t.Errorf("ejection %d does not last until %v", b.ejections, want)
+		}
+	}
+
+	// A success ends the consecutive ejections
+	b.observe(now, now, nil, o)
+	b.eject(now, o)
+	if got := b.ejectedUntil.Sub(now); got != time.Second {
+		t.Errorf("ejection after a success lasts %v, want 1s", got)
+	}
+}
+
+func TestMaxEjectedPercent(t *testing.T) {
+	pb := &pickerBuilder{
+		opts:     newOptions([]Option{WithEjection(1, time.Minute, time.Minute), WithMaxEjectedPercent(50)}),
+		backends: make(map[string]*backend),
+	}
+	p := pb.Build(readySCs("a", "b", "c", "d")).(*picker)
+	unavailable := balancer.DoneInfo{Err: status.Error(codes.Unavailable, "down")}
+
+	// Every backend fails, but at most half of them are ejected
+	for _, b := range p.backends {
+		b.outstanding++
+		p.done(b, time.Now(), unavailable)
+	}
+	now := time.Now()
+	if n := p.ejected(now); n != 2 {
+		t.Fatalf("%d backends ejected, want 2 of 4", n)
+	}
+
+	// The calls only go to the backends left
+	for i := 0; i < 100; i++ {
+		sc, done, err := p.Pick(context.Background(), balancer.PickOptions{})
+		if err != nil {
+			t.Fatalf("Pick() failed: %v", err)
+		}
+		if b := pb.backends[sc.(*fakeSubConn).addr]; b.ejected(now) {
+			t.Fatalf("Pick() returned the ejected backend %s", b.addr)
+		}
+		done(balancer.DoneInfo{})
+	}
+
+	// With a single backend, it is never ejected
+	pb = &pickerBuilder{opts: pb.opts, backends: make(map[string]*backend)}
+	p = pb.Build(readySCs("a")).(*picker)
+	p.backends[0].outstanding++
+	p.done(p.backends[0], time.Now(), unavailable)
+	if n := p.ejected(time.Now()); n != 0 {
+		t.Errorf("the only backend was ejected")
+	}
+}
+
+func TestBackendsKeptWhileResolved(t *testing.T) {
+	pb := &pickerBuilder{opts: newOptions(nil), backends: make(map[string]*backend)}
+	pb.Build(readySCs("a", "b"))
+	a := pb.backends["a"]
+	a.latency = float64(time.Second)
+
+	// a reconnects: it is not ready for a while, but keeps its latency
+	pb.Build(readySCs("b"))
+	pb.resolved([]resolver.Address{{Addr: "a"}, {Addr: "b"}})
+	pb.Build(readySCs("a", "b"))
+	if pb.backends["a"] != a {
+		t.Errorf("the backend of a was dropped while a was resolved")
+	}
+
+	// a is removed from the resolver state
+	pb.resolved([]resolver.Address{{Addr: "b"}})
+	if _, ok := pb.backends["a"]; ok {
+		t.Errorf("the backend of a was kept after a was no longer resolved")
+	}
+}
+
+// slowServer is an Echo server taking delay to answer.
+type slowServer struct {
+	echoserver.Server
+	delay time.Duration
+}
+
+func (s *slowServer) UnaryEcho(ctx context.Context, req *echopb.EchoRequest) (*echopb.EchoResponse, error) {
+	time.Sleep(s.delay)
+	return &echopb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// p99 returns the 99th percentile of the latencies of n calls to addrs, made
+// by callers goroutines and balanced by policy.
+func p99(t *testing.T, policy string, addrs []string, callers, n int) time.Duration {
+	conn, err := grpc.Dial(discovery.ListTarget(addrs...), grpc.WithInsecure(),
+		grpc.WithBalancerName(policy))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	c := echopb.NewEchoClient(conn)
+	time.Sleep(200 * time.Millisecond) // the time to connect to all of them
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+	var wg sync.WaitGroup
+	calls := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		calls <- struct{}{}
+	}
+	close(calls)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range calls {
+				start := time.Now()
+				if _, err := c.UnaryEcho(context.Background(), &echopb.EchoRequest{Message: "hello"}); err != nil {
+					t.Errorf("UnaryEcho() failed: %v", err)
+					return
+				}
+				mu.Lock()
+				latencies = append(latencies, time.Since(start))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[len(latencies)*99/100]
+}
+
+func TestSkewedLoadP99(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compares the latencies of hundreds of calls")
+	}
+	// The first backend is busy, e.g. with huge requests, and answers slowly
+	var addrs []string
+	for _, delay := range []time.Duration{50 * time.Millisecond, time.Millisecond, time.Millisecond} {
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		s := grpc.NewServer()
+		echopb.RegisterEchoServer(s, &slowServer{delay: delay})
+		go s.Serve(lis)
+		defer s.Stop()
+		addrs = append(addrs, lis.Addr().String())
+	}
+
+	rr := p99(t, "round_robin", addrs, 4, 600)
+	p := p99(t, Name, addrs, 4, 600)
+	t.Logf("p99: round_robin %v, p2c %v", rr, p)
+	if p >= rr/2 {
+		t.Errorf("the p99 latency of p2c is %v, want less than half the %v of round_robin", p, rr)
+	}
+}
+
+func BenchmarkPick(b *testing.B) {
+	pb := &pickerBuilder{opts: newOptions(nil), backends: make(map[string]*backend)}
+	p := pb.Build(readySCs("a", "b", "c", "d", "e", "f", "g", "h"))
+	info := balancer.DoneInfo{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, done, err := p.Pick(context.Background(), balancer.PickOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		done(info)
+	}
+}
@@ -0,0 +1,101 @@
+// Package recovery provides server interceptors that recover from the panics of
+// the handlers, so that a bug fails the call instead of crashing the server.
+// Chain them after the logging and metrics interceptors, which then see the
+// Internal errors of the calls that panicked.
+package recovery
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+
+	"github.com/wangy8961/grpc-go-tutorial/logging"
+	"github.com/wangy8961/grpc-go-tutorial/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Hook is called with the panic p of a call of method and the stack of the
+// panicking goroutine, e.g. to report it to an error tracker. ctx is the
+// context of the call, with its request ID and trace span.
+type Hook func(ctx context.Context, method string, p interface{}, stack []byte)
+
+// Option configures the recovery interceptors.
+type Option func(*options)
+
+type options struct {
+	metrics *metrics.Metrics
+	hook    Hook
+}
+
+// WithMetrics counts the panics in m, the metrics of the server, as
+// grpc_server_panics_recovered_total.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *options) { o.metrics = m }
+}
+
+// WithHook calls hook for every panic, after it has been logged.
+func WithHook(hook Hook) Option {
+	return func(o *options) { o.hook = hook }
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// recovered handles the panic p of a call of method and returns the error of the call.
+func (o *options) recovered(ctx context.Context, method string, p interface{}) error {
+	stack := debug.Stack()
+	log.Printf("panic in %s (request %s): %v\n%s", method, logging.RequestID(ctx), p, stack)
+	if o.metrics != nil {
+		o.metrics.Panicked(method)
+	}
+	if o.hook != nil {
+		o.callHook(ctx, method, p, stack)
+	}
+	// The panic value may hold internal details, it is not sent to the caller
+	return status.Errorf(codes.Internal, "internal error")
+}
+
+// callHook calls the hook, whose own panics must not crash the server either.
+func (o *options) callHook(ctx context.Context, method string, p interface{}, stack []byte) {
+	defer func() {
+		if hp := recover(); hp != nil {
+			log.Printf("panic in recovery hook of %s: %v", method, hp)
+		}
+	}()
+	o.hook(ctx, method, p, stack)
+}
+
+// UnaryServerInterceptor returns a server-side unary interceptor that turns the
+// panics of the handler into Internal errors.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = o.recovered(ctx, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that
+// turns the panics of the handler into Internal errors.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = o.recovered(ss.Context(), info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryInterval is how long a Registration waits before registering again
+// once the registry is unreachable.
+const retryInterval = time.Second
+
+// Registration keeps an instance registered in a registry, see Register.
+type Registration struct {
+	client pb.RegistryClient
+	inst   *pb.Instance
+	ttl    time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{} // closed once run returns
+	once   sync.Once
+	err    error // of Deregister
+}
+
+// Register registers inst in the registry of conn for ttl, the default TTL of
+// the registry if 0, and keeps it registered by sending heartbeats three times
+// per TTL until Deregister is called. It registers inst again whenever the
+// registry forgets it, e.g. when the registry restarts, and retries in the
+// background while the registry is unreachable.
+func Register(conn *grpc.ClientConn, inst *pb.Instance, ttl time.Duration) *Registration {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Registration{
+		client: pb.NewRegistryClient(conn),
+		inst:   inst,
+		ttl:    ttl,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go r.run(ctx)
+	return r
+}
+
+// run registers the instance then sends the heartbeats, until ctx is done.
+func (r *Registration) run(ctx context.Context) {
+	defer close(r.done)
+	for {
+		ttl, err := r.register(ctx)
+		if err == nil {
+			err = r.heartbeat(ctx, ttl)
+			if status.Code(err) == codes.NotFound {
+				log.Printf("registry: %s was forgotten, registering it again", r.inst.Address)
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("registry: failed to keep %s registered, retrying in %v: %v", r.inst.Address, retryInterval, err)
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// register registers the instance and returns its TTL.
+func (r *Registration) register(ctx context.Context) (time.Duration, error) {
+	req := &pb.RegisterRequest{Instance: r.inst}
+	if r.ttl > 0 {
+		req.Ttl = ptypes.DurationProto(r.ttl)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, retryInterval)
+	defer cancel()
+	resp, err := r.client.Register(callCtx, req)
+	if err != nil {
+		return 0, err
+	}
+	return ptypes.Duration(resp.Ttl)
+}
+
+// heartbeat sends the heartbeats of the instance until ctx is done, or the
+// registry forgets the instance.
+func (r *Registration) heartbeat(ctx context.Context, ttl time.Duration) error {
+	// Three heartbeats per TTL, the instance does not expire if one is lost
+	t := time.NewTicker(ttl / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		callCtx, cancel := context.WithTimeout(ctx, ttl/3)
+		_, err := r.client.Heartbeat(callCtx, &pb.HeartbeatRequest{Address: r.inst.Address})
+		cancel()
+		switch {
+		case status.Code(err) == codes.NotFound:
+			// Expired, or the registry restarted
+			return err
+		case err != nil && ctx.Err() == nil:
+			log.Printf("registry: failed to send the heartbeat of %s: %v", r.inst.Address, err)
+		}
+	}
+}
+
+// Deregister stops the heartbeats and removes the instance from the registry,
+// so that the clients stop sending it new calls at once. The following calls
+// return the error of the first one.
+func (r *Registration) Deregister(ctx context.Context) error {
+	r.once.Do(func() {
+		r.cancel()
+		<-r.done
+		_, r.err = r.client.Deregister(ctx, &pb.DeregisterRequest{Address: r.inst.Address})
+	})
+	return r.err
+}
@@ -0,0 +1,247 @@
+// Package registry implements the registry service of registrypb in memory:
+// the servers register their instances, and the clients watch the instances
+// of a service, e.g. through the registry:// resolver of package discovery.
+//
+// An instance stays registered for its TTL after its registration and each of
+// its heartbeats: the instances of the servers killed or partitioned away
+// expire, while the servers shutting down gracefully deregister theirs. See
+// Register for the servers side.
+package registry
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBuffer is the number of changes buffered for a watcher. A watcher
+// falling further behind is dropped, and gets the instances again once it
+// watches again.
+const watchBuffer = 64
+
+const (
+	// DefaultTTL is the TTL of the instances registered without one.
+	DefaultTTL = 10 * time.Second
+	// MinTTL is the minimum TTL, the shorter ones are raised to it.
+	MinTTL = time.Second
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithDefaultTTL sets the TTL of the instances registered without one,
+// DefaultTTL by default.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.defaultTTL = ttl
+	}
+}
+
+// Server implements registrypb.RegistryServer.
+type Server struct {
+	defaultTTL time.Duration
+
+	mu        sync.Mutex
+	instances map[string]*entry // by address
+	watchers  map[*watcher]struct{}
+}
+
+// entry is a registered instance.
+type entry struct {
+	inst    *pb.Instance
+	ttl     time.Duration
+	expires time.Time
+	timer   *time.Timer // expires the entry
+}
+
+// watcher is a Watch call.
+type watcher struct {
+	service string
+	events  chan *pb.Event // closed when the watcher is dropped
+}
+
+// New returns a registry without instances.
+func New(opts ...Option) *Server {
+	s := &Server{
+		defaultTTL: DefaultTTL,
+		instances:  make(map[string]*entry),
+		watchers:   make(map[*watcher]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register implements registrypb.RegistryServer
+func (s *Server) Register(ctx context.Context, in *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	if in.GetInstance().GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "the address of the instance is required")
+	}
+	ttl := s.defaultTTL
+	if in.Ttl != nil {
+		d, err := ptypes.Duration(in.Ttl)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid TTL: %v", err)
+		}
+		ttl = d
+	}
+	if ttl < MinTTL {
+		ttl = MinTTL
+	}
+	s.register(proto.Clone(in.Instance).(*pb.Instance), ttl)
+	return &pb.RegisterResponse{Ttl: ptypes.DurationProto(ttl)}, nil
+}
+
+// Heartbeat implements registrypb.RegistryServer
+func (s *Server) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.instances[in.Address]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s is not registered, or expired", in.Address)
+	}
+	e.expires = time.Now().Add(e.ttl)
+	e.timer.Reset(e.ttl)
+	return &pb.HeartbeatResponse{}, nil
+}
+
+// Deregister implements registrypb.RegistryServer
+func (s *Server) Deregister(ctx context.Context, in *pb.DeregisterRequest) (*pb.DeregisterResponse, error) {
+	s.deregister(in.Address)
+	return &pb.DeregisterResponse{}, nil
+}
+
+// Watch implements registrypb.RegistryServer
+func (s *Server) Watch(in *pb.WatchRequest, stream pb.Registry_WatchServer) error {
+	if in.Service == "" {
+		return status.Error(codes.InvalidArgument, "the service is required")
+	}
+	w := &watcher{service: in.Service, events: make(chan *pb.Event, watchBuffer)}
+
+	// The instances registered so far, then the changes
+	s.mu.Lock()
+	var events []*pb.Event
+	for _, e := range s.instances {
+		if serves(e.inst, in.Service) {
+			events = append(events, &pb.Event{Type: pb.Event_ADD, Instance: e.inst})
+		}
+	}
+	s.watchers[w] = struct{}{}
+	s.mu.Unlock()
+	defer s.removeWatcher(w)
+
+	if err := stream.Send(&pb.WatchResponse{Events: events}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case e, ok := <-w.events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "too many changes not received, watch again")
+			}
+			if err := stream.Send(&pb.WatchResponse{Events: []*pb.Event{e}}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+// register adds inst for ttl, replacing the instance at its address if any.
+func (s *Server) register(inst *pb.Instance, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var old *pb.Instance
+	if e, ok := s.instances[inst.Address]; ok {
+		e.timer.Stop()
+		old = e.inst
+	}
+	e := &entry{inst: inst, ttl: ttl, expires: time.Now().Add(ttl)}
+	e.timer = time.AfterFunc(ttl, func() { s.expire(e) })
+	s.instances[inst.Address] = e
+	if old == nil {
+		log.Printf("registry: registered %s for %v with a TTL of %v", inst.Address, inst.Services, ttl)
+	}
+	for w := range s.watchers {
+		switch {
+		case serves(inst, w.service):
+			if old == nil || !proto.Equal(old, inst) {
+				s.notify(w, &pb.Event{Type: pb.Event_ADD, Instance: inst})
+			}
+		case old != nil && serves(old, w.service):
+			// The instance does not serve the service anymore
+			s.notify(w, &pb.Event{Type: pb.Event_REMOVE, Instance: old})
+		}
+	}
+}
+
+// deregister removes the instance at addr, if any.
+func (s *Server) deregister(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.instances[addr]
+	if !ok {
+		return
+	}
+	e.timer.Stop()
+	s.remove(e)
+	log.Printf("registry: deregistered %s", addr)
+}
+
+// expire removes e if it was not renewed or replaced meanwhile.
+func (s *Server) expire(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.instances[e.inst.Address] != e || time.Now().Before(e.expires) {
+		// Replaced, or renewed by a heartbeat while the timer fired
+		return
+	}
+	s.remove(e)
+	log.Printf("registry: %s expired, no heartbeat for %v", e.inst.Address, e.ttl)
+}
+
+// remove removes e and notifies the watchers. s.mu must be held.
+func (s *Server) remove(e *entry) {
+	delete(s.instances, e.inst.Address)
+	for w := range s.watchers {
+		if serves(e.inst, w.service) {
+			s.notify(w, &pb.Event{Type: pb.Event_REMOVE, Instance: e.inst})
+		}
+	}
+}
+
+// notify sends the event to w, or drops w if it falls too far behind. s.mu
+// must be held.
+func (s *Server) notify(w *watcher, e *pb.Event) {
+	select {
+	case w.events <- e:
+	default:
+		delete(s.watchers, w)
+		close(w.events)
+	}
+}
+
+func (s *Server) removeWatcher(w *watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.watchers, w)
+}
+
+// serves reports whether inst serves service.
+func serves(inst *pb.Instance, service string) bool {
+	for _, name := range inst.Services {
+		if name == service {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// Package main implements a server for Registry service, the service discovery
+// of the examples on a developer laptop, without Consul or Kubernetes.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/registry"
+	pb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"github.com/wangy8961/grpc-go-tutorial/validate"
+)
+
+func main() {
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.Address = ":50050"
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	defaultTTL := flag.Duration("default-ttl", registry.DefaultTTL, "how long the instances registered without a TTL stay registered without heartbeats")
+	flag.Parse()
+
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	s, err := bootstrap.NewServer(cfg,
+		// Reject requests that violate the (validate.rules) declared in registry.proto
+		bootstrap.WithUnaryInterceptors(validate.UnaryServerInterceptor()),
+		bootstrap.WithStreamInterceptors(validate.StreamServerInterceptor()),
+	)
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+	pb.RegisterRegistryServer(s.Server, registry.New(registry.WithDefaultTTL(*defaultTTL)))
+	if err := s.Serve(); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
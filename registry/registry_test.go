@@ -0,0 +1,214 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/wangy8961/grpc-go-tutorial/registry/registrypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ttl is the TTL of the tests, registered with register as Register raises
+// it to MinTTL.
+const ttl = 50 * time.Millisecond
+
+func instance(addr string, services ...string) *pb.Instance {
+	return &pb.Instance{Address: addr, Services: services}
+}
+
+func (s *Server) registered(addr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.instances[addr]
+	return ok
+}
+
+func heartbeat(s *Server, addr string) error {
+	_, err := s.Heartbeat(context.Background(), &pb.HeartbeatRequest{Address: addr})
+	return err
+}
+
+func TestExpiry(t *testing.T) {
+	s := New()
+	s.register(instance("a:1", "echo.Echo"), ttl)
+
+	// The heartbeats keep the instance registered beyond its TTL
+	for i := 0; i < 4; i++ {
+		time.Sleep(ttl / 2)
+		if err := heartbeat(s, "a:1"); err != nil {
+			t.Fatalf("Heartbeat() failed: %v", err)
+		}
+	}
+	if !s.registered("a:1") {
+		t.Fatalf("a:1 expired despite its heartbeats")
+	}
+
+	time.Sleep(2 * ttl)
+	if s.registered("a:1") {
+		t.Errorf("a:1 is still registered %v after its last heartbeat, want it expired", 2*ttl)
+	}
+}
+
+func TestHeartbeatWhileTimerFires(t *testing.T) {
+	s := New()
+	s.register(instance("a:1", "echo.Echo"), ttl)
+	s.mu.Lock()
+	e := s.instances["a:1"]
+	e.timer.Stop()
+	s.mu.Unlock()
+
+	// The timer fired, but the heartbeat got the lock first: expire runs after it
+	time.Sleep(ttl)
+	if err := heartbeat(s, "a:1"); err != nil {
+		t.Fatalf("Heartbeat() failed: %v", err)
+	}
+	s.expire(e)
+	if !s.registered("a:1") {
+		t.Fatalf("a:1 expired right after its heartbeat")
+	}
+
+	// Without the heartbeat, the same call expires it
+	time.Sleep(2 * ttl)
+	s.expire(e)
+	if s.registered("a:1") {
+		t.Errorf("a:1 is still registered without a heartbeat")
+	}
+}
+
+func TestRegisterAfterNotFound(t *testing.T) {
+	s := New()
+	s.register(instance("a:1", "echo.Echo"), ttl)
+	time.Sleep(2 * ttl)
+
+	// The server lost its registration, e.g. while partitioned away: it registers again
+	if err := heartbeat(s, "a:1"); status.Code(err) != codes.NotFound {
+		t.Fatalf("Heartbeat() of an expired instance = %v, want NotFound", err)
+	}
+	res, err := s.Register(context.Background(), &pb.RegisterRequest{Instance: instance("a:1", "echo.Echo")})
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	if res.GetTtl().GetSeconds() != int64(DefaultTTL/time.Second) {
+		t.Errorf("Register() returned a TTL of %v, want %v", res.GetTtl(), DefaultTTL)
+	}
+	if err := heartbeat(s, "a:1"); err != nil {
+		t.Errorf("Heartbeat() after registering again failed: %v", err)
+	}
+}
+
+// watchStream is a Registry_WatchServer passing the responses to the test,
+// which receives them from responses.
+type watchStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *pb.WatchResponse
+}
+
+func (s *watchStream) Context() context.Context { return s.ctx }
+
+func (s *watchStream) Send(res *pb.WatchResponse) error {
+	select {
+	case s.responses <- res:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// watch starts watching service on s, and returns the stream, the channel of
+// the error of Watch, and a function canceling the watch.
+func watch(s *Server, service string) (*watchStream, chan error, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &watchStream{ctx: ctx, responses: make(chan *pb.WatchResponse)}
+	errc := make(chan error, 1)
+	go func() { errc <- s.Watch(&pb.WatchRequest{Service: service}, stream) }()
+	return stream, errc, cancel
+}
+
+// next returns the next event received by stream.
+func next(t *testing.T, stream *watchStream) *pb.Event {
+	select {
+	case res := <-stream.responses:
+		if len(res.Events) != 1 {
+			t.Fatalf("received %d events, want 1", len(res.Events))
+		}
+		return res.Events[0]
+	case <-time.After(time.Second):
+		t.Fatalf("no event received")
+		return nil
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := New()
+	s.register(instance("a:1", "echo.Echo"), time.Minute)
+	s.register(instance("b:1", "math.Math"), time.Minute)
+
+	stream, _, cancel := watch(s, "echo.Echo")
+	defer cancel()
+	// The instances of the service registered so far
+	res := <-stream.responses
+	if len(res.Events) != 1 || res.Events[0].Type != pb.Event_ADD || res.Events[0].Instance.Address != "a:1" {
+		t.Fatalf("received %v, want the ADD of a:1", res.Events)
+	}
+
+	s.register(instance("c:1", "echo.Echo"), ttl)
+	if e := next(t, stream); e.Type != pb.Event_ADD || e.Instance.Address != "c:1" {
+		t.Errorf("received %v, want the ADD of c:1", e)
+	}
+	// The instances of the other services are not watched
+	s.register(instance("d:1", "math.Math"), time.Minute)
+	// c:1 expires
+	if e := next(t, stream); e.Type != pb.Event_REMOVE || e.Instance.Address != "c:1" {
+		t.Errorf("received %v, want the REMOVE of c:1", e)
+	}
+	// a:1 no longer serves the service
+	s.register(instance("a:1", "math.Math"), time.Minute)
+	if e := next(t, stream); e.Type != pb.Event_REMOVE || e.Instance.Address != "a:1" {
+		t.Errorf("received %v, want the REMOVE of a:1", e)
+	}
+	s.deregister("a:1")
+	s.register(instance("e:1", "echo.Echo"), time.Minute)
+	if e := next(t, stream); e.Type != pb.Event_ADD || e.Instance.Address != "e:1" {
+		t.Errorf("received %v, want the ADD of e:1", e)
+	}
+}
+
+func TestSlowWatcherDropped(t *testing.T) {
+	s := New()
+	stream, errc, cancel := watch(s, "echo.Echo")
+	defer cancel()
+	<-stream.responses
+
+	// The watcher does not receive, while more changes than it buffers are made
+	for i := 0; i < watchBuffer+2; i++ {
+		s.register(instance("a:1", "echo.Echo"), time.Minute)
+		s.deregister("a:1")
+	}
+	s.mu.Lock()
+	n := len(s.watchers)
+	s.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d watchers left, want the slow one dropped", n)
+	}
+
+	// It gets the changes buffered, then the end of the watch
+	for received := 0; ; received++ {
+		select {
+		case <-stream.responses:
+		case err := <-errc:
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("Watch() = %v, want ResourceExhausted", err)
+			}
+			if received > watchBuffer+1 {
+				t.Errorf("received %d changes, want at most the %d buffered and the one being sent", received, watchBuffer)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatalf("Watch() did not end")
+		}
+	}
+}
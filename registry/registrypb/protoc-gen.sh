@@ -0,0 +1,3 @@
+#!/bin/bash
+
+protoc -I. -I../.. --go_out=plugins=grpc:. *.proto
\ No newline at end of file
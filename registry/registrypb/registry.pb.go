@@ -0,0 +1,770 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: registry.proto
+
+package registrypb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	_ "github.com/wangy8961/grpc-go-tutorial/validate"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Event_Type int32
+
+const (
+	// The instance was added, or replaced the instance at its address.
+	Event_ADD Event_Type = 0
+	// The instance was removed.
+	Event_REMOVE Event_Type = 1
+)
+
+var Event_Type_name = map[int32]string{
+	0: "ADD",
+	1: "REMOVE",
+}
+
+var Event_Type_value = map[string]int32{
+	"ADD":    0,
+	"REMOVE": 1,
+}
+
+func (x Event_Type) String() string {
+	return proto.EnumName(Event_Type_name, int32(x))
+}
+
+func (Event_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{8, 0}
+}
+
+// An instance of a server.
+type Instance struct {
+	// The address of the instance, e.g. "10.0.0.1:50051", which identifies it.
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// The full names of the services of the instance, e.g. "math.Math".
+	Services []string `protobuf:"bytes,2,rep,name=services,proto3" json:"services,omitempty"`
+	// The relative share of the calls sent to the instance, 1 if 0.
+	Weight int32 `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	// The attributes of the instance, e.g. zone: eu-west-1a.
+	Metadata             map[string]string `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Instance) Reset()         { *m = Instance{} }
+func (m *Instance) String() string { return proto.CompactTextString(m) }
+func (*Instance) ProtoMessage()    {}
+func (*Instance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{0}
+}
+
+func (m *Instance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Instance.Unmarshal(m, b)
+}
+func (m *Instance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Instance.Marshal(b, m, deterministic)
+}
+func (m *Instance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Instance.Merge(m, src)
+}
+func (m *Instance) XXX_Size() int {
+	return xxx_messageInfo_Instance.Size(m)
+}
+func (m *Instance) XXX_DiscardUnknown() {
+	xxx_messageInfo_Instance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Instance proto.InternalMessageInfo
+
+func (m *Instance) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Instance) GetServices() []string {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+func (m *Instance) GetWeight() int32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+func (m *Instance) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+// The request message for Register.
+type RegisterRequest struct {
+	Instance *Instance `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	// How long the instance stays registered without heartbeats, the default
+	// TTL of the registry if not set.
+	Ttl                  *duration.Duration `protobuf:"bytes,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *RegisterRequest) Reset()         { *m = RegisterRequest{} }
+func (m *RegisterRequest) String() string { return proto.CompactTextString(m) }
+func (*RegisterRequest) ProtoMessage()    {}
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{1}
+}
+
+func (m *RegisterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterRequest.Unmarshal(m, b)
+}
+func (m *RegisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterRequest.Marshal(b, m, deterministic)
+}
+func (m *RegisterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterRequest.Merge(m, src)
+}
+func (m *RegisterRequest) XXX_Size() int {
+	return xxx_messageInfo_RegisterRequest.Size(m)
+}
+func (m *RegisterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterRequest proto.InternalMessageInfo
+
+func (m *RegisterRequest) GetInstance() *Instance {
+	if m != nil {
+		return m.Instance
+	}
+	return nil
+}
+
+func (m *RegisterRequest) GetTtl() *duration.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+// The response message for Register.
+type RegisterResponse struct {
+	// The TTL of the instance, which sends its heartbeats more often.
+	Ttl                  *duration.Duration `protobuf:"bytes,1,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *RegisterResponse) Reset()         { *m = RegisterResponse{} }
+func (m *RegisterResponse) String() string { return proto.CompactTextString(m) }
+func (*RegisterResponse) ProtoMessage()    {}
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{2}
+}
+
+func (m *RegisterResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterResponse.Unmarshal(m, b)
+}
+func (m *RegisterResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterResponse.Marshal(b, m, deterministic)
+}
+func (m *RegisterResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterResponse.Merge(m, src)
+}
+func (m *RegisterResponse) XXX_Size() int {
+	return xxx_messageInfo_RegisterResponse.Size(m)
+}
+func (m *RegisterResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterResponse proto.InternalMessageInfo
+
+func (m *RegisterResponse) GetTtl() *duration.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+// The request message for Heartbeat.
+type HeartbeatRequest struct {
+	Address              string   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HeartbeatRequest) Reset()         { *m = HeartbeatRequest{} }
+func (m *HeartbeatRequest) String() string { return proto.CompactTextString(m) }
+func (*HeartbeatRequest) ProtoMessage()    {}
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{3}
+}
+
+func (m *HeartbeatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeartbeatRequest.Unmarshal(m, b)
+}
+func (m *HeartbeatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeartbeatRequest.Marshal(b, m, deterministic)
+}
+func (m *HeartbeatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeartbeatRequest.Merge(m, src)
+}
+func (m *HeartbeatRequest) XXX_Size() int {
+	return xxx_messageInfo_HeartbeatRequest.Size(m)
+}
+func (m *HeartbeatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeartbeatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeartbeatRequest proto.InternalMessageInfo
+
+func (m *HeartbeatRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+// The response message for Heartbeat.
+type HeartbeatResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HeartbeatResponse) Reset()         { *m = HeartbeatResponse{} }
+func (m *HeartbeatResponse) String() string { return proto.CompactTextString(m) }
+func (*HeartbeatResponse) ProtoMessage()    {}
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{4}
+}
+
+func (m *HeartbeatResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeartbeatResponse.Unmarshal(m, b)
+}
+func (m *HeartbeatResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeartbeatResponse.Marshal(b, m, deterministic)
+}
+func (m *HeartbeatResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeartbeatResponse.Merge(m, src)
+}
+func (m *HeartbeatResponse) XXX_Size() int {
+	return xxx_messageInfo_HeartbeatResponse.Size(m)
+}
+func (m *HeartbeatResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeartbeatResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeartbeatResponse proto.InternalMessageInfo
+
+// The request message for Deregister.
+type DeregisterRequest struct {
+	Address              string   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeregisterRequest) Reset()         { *m = DeregisterRequest{} }
+func (m *DeregisterRequest) String() string { return proto.CompactTextString(m) }
+func (*DeregisterRequest) ProtoMessage()    {}
+func (*DeregisterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{5}
+}
+
+func (m *DeregisterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeregisterRequest.Unmarshal(m, b)
+}
+func (m *DeregisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeregisterRequest.Marshal(b, m, deterministic)
+}
+func (m *DeregisterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeregisterRequest.Merge(m, src)
+}
+func (m *DeregisterRequest) XXX_Size() int {
+	return xxx_messageInfo_DeregisterRequest.Size(m)
+}
+func (m *DeregisterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeregisterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeregisterRequest proto.InternalMessageInfo
+
+func (m *DeregisterRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+// The response message for Deregister.
+type DeregisterResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeregisterResponse) Reset()         { *m = DeregisterResponse{} }
+func (m *DeregisterResponse) String() string { return proto.CompactTextString(m) }
+func (*DeregisterResponse) ProtoMessage()    {}
+func (*DeregisterResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{6}
+}
+
+func (m *DeregisterResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeregisterResponse.Unmarshal(m, b)
+}
+func (m *DeregisterResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeregisterResponse.Marshal(b, m, deterministic)
+}
+func (m *DeregisterResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeregisterResponse.Merge(m, src)
+}
+func (m *DeregisterResponse) XXX_Size() int {
+	return xxx_messageInfo_DeregisterResponse.Size(m)
+}
+func (m *DeregisterResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeregisterResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeregisterResponse proto.InternalMessageInfo
+
+// The request message for Watch.
+type WatchRequest struct {
+	// The full name of the service, e.g. "math.Math".
+	Service              string   `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{7}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+// A change of the instances of a service.
+type Event struct {
+	Type                 Event_Type `protobuf:"varint,1,opt,name=type,proto3,enum=registry.Event_Type" json:"type,omitempty"`
+	Instance             *Instance  `protobuf:"bytes,2,opt,name=instance,proto3" json:"instance,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{8}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetType() Event_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Event_ADD
+}
+
+func (m *Event) GetInstance() *Instance {
+	if m != nil {
+		return m.Instance
+	}
+	return nil
+}
+
+// The response message for Watch, the changes of the instances since the
+// previous one.
+type WatchResponse struct {
+	Events               []*Event `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchResponse) Reset()         { *m = WatchResponse{} }
+func (m *WatchResponse) String() string { return proto.CompactTextString(m) }
+func (*WatchResponse) ProtoMessage()    {}
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{9}
+}
+
+func (m *WatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchResponse.Unmarshal(m, b)
+}
+func (m *WatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchResponse.Marshal(b, m, deterministic)
+}
+func (m *WatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchResponse.Merge(m, src)
+}
+func (m *WatchResponse) XXX_Size() int {
+	return xxx_messageInfo_WatchResponse.Size(m)
+}
+func (m *WatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchResponse proto.InternalMessageInfo
+
+func (m *WatchResponse) GetEvents() []*Event {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("registry.Event_Type", Event_Type_name, Event_Type_value)
+	proto.RegisterType((*Instance)(nil), "registry.Instance")
+	proto.RegisterMapType((map[string]string)(nil), "registry.Instance.MetadataEntry")
+	proto.RegisterType((*RegisterRequest)(nil), "registry.RegisterRequest")
+	proto.RegisterType((*RegisterResponse)(nil), "registry.RegisterResponse")
+	proto.RegisterType((*HeartbeatRequest)(nil), "registry.HeartbeatRequest")
+	proto.RegisterType((*HeartbeatResponse)(nil), "registry.HeartbeatResponse")
+	proto.RegisterType((*DeregisterRequest)(nil), "registry.DeregisterRequest")
+	proto.RegisterType((*DeregisterResponse)(nil), "registry.DeregisterResponse")
+	proto.RegisterType((*WatchRequest)(nil), "registry.WatchRequest")
+	proto.RegisterType((*Event)(nil), "registry.Event")
+	proto.RegisterType((*WatchResponse)(nil), "registry.WatchResponse")
+}
+
+func init() { proto.RegisterFile("registry.proto", fileDescriptor_41af05d40a615591) }
+
+var fileDescriptor_41af05d40a615591 = []byte{
+	// 542 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0xdb, 0x6e, 0xd3, 0x40,
+	0x10, 0xad, 0xed, 0x26, 0x75, 0xa6, 0x4d, 0xeb, 0x0e, 0x11, 0x75, 0x5d, 0x84, 0x2c, 0x4b, 0x88,
+	0x48, 0x48, 0x4e, 0x09, 0x17, 0x55, 0x50, 0x09, 0x11, 0x12, 0x44, 0x1f, 0x2a, 0xa4, 0x15, 0x02,
+	0x89, 0xb7, 0x4d, 0x32, 0xa4, 0x16, 0xc1, 0x0e, 0xeb, 0x4d, 0x50, 0x5e, 0xf8, 0x16, 0xbe, 0xa7,
+	0x9f, 0xc2, 0x6b, 0x7f, 0x00, 0xd9, 0x5e, 0x5f, 0x48, 0x82, 0x8a, 0x9f, 0x76, 0xe7, 0x9c, 0x39,
+	0x33, 0x3e, 0x67, 0x61, 0x5f, 0xd0, 0x24, 0x88, 0xa5, 0x58, 0xfa, 0x33, 0x11, 0xc9, 0x08, 0xcd,
+	0xfc, 0xee, 0xdc, 0x9f, 0x44, 0xd1, 0x64, 0x4a, 0x9d, 0xb4, 0x3e, 0x9c, 0x7f, 0xe9, 0x8c, 0xe7,
+	0x82, 0xcb, 0x20, 0x0a, 0x33, 0xa6, 0x73, 0xb4, 0xe0, 0xd3, 0x60, 0xcc, 0x25, 0x75, 0xf2, 0x43,
+	0x06, 0x78, 0xbf, 0x35, 0x30, 0x2f, 0xc2, 0x58, 0xf2, 0x70, 0x44, 0xe8, 0xc2, 0x0e, 0x1f, 0x8f,
+	0x05, 0xc5, 0xb1, 0xad, 0xb9, 0x5a, 0xbb, 0xd1, 0xab, 0x5f, 0xdf, 0xd8, 0xba, 0xa5, 0xb1, 0xbc,
+	0x8c, 0x0e, 0x98, 0x31, 0x89, 0x45, 0x30, 0xa2, 0xd8, 0xd6, 0x5d, 0xa3, 0xdd, 0x60, 0xc5, 0x1d,
+	0x1f, 0x40, 0xfd, 0x07, 0x05, 0x93, 0x2b, 0x69, 0x1b, 0xae, 0xd6, 0xae, 0xf5, 0x9a, 0xd7, 0x37,
+	0x76, 0xe3, 0xf1, 0x96, 0xfa, 0x98, 0x02, 0xf1, 0x1c, 0xcc, 0x6f, 0x24, 0xf9, 0x98, 0x4b, 0x6e,
+	0x6f, 0xbb, 0x46, 0x7b, 0xb7, 0xeb, 0xfa, 0xc5, 0x7f, 0xe5, 0xab, 0xf8, 0x97, 0x8a, 0x32, 0x08,
+	0xa5, 0x58, 0xb2, 0xa2, 0xc3, 0x79, 0x09, 0xcd, 0xbf, 0x20, 0xb4, 0xc0, 0xf8, 0x4a, 0xcb, 0x6c,
+	0x5f, 0x96, 0x1c, 0xb1, 0x05, 0xb5, 0x05, 0x9f, 0xce, 0xc9, 0xd6, 0xd3, 0x5a, 0x76, 0x79, 0xa1,
+	0x9f, 0x69, 0xde, 0x02, 0x0e, 0x58, 0x3a, 0x89, 0x04, 0xa3, 0xef, 0x73, 0x8a, 0x25, 0x3e, 0x07,
+	0x33, 0x50, 0x33, 0x53, 0x8d, 0xdd, 0x2e, 0xae, 0x6f, 0x93, 0xf9, 0x60, 0x6a, 0xac, 0xe0, 0xe2,
+	0x23, 0x30, 0xa4, 0x9c, 0xa6, 0x23, 0x76, 0xbb, 0xc7, 0x7e, 0x66, 0xbf, 0x9f, 0xdb, 0xef, 0xf7,
+	0x95, 0xfd, 0x2c, 0x61, 0x79, 0xaf, 0xc0, 0x2a, 0xe7, 0xc6, 0xb3, 0x28, 0x8c, 0x0b, 0x01, 0xed,
+	0xbf, 0x04, 0x9e, 0x82, 0xf5, 0x8e, 0xb8, 0x90, 0x43, 0xe2, 0x32, 0xdf, 0xfc, 0xd6, 0xb0, 0xbc,
+	0x3b, 0x70, 0x58, 0xe9, 0xca, 0xe6, 0x7a, 0xcf, 0xe0, 0xb0, 0x4f, 0x62, 0xc5, 0x85, 0xdb, 0xb5,
+	0x5a, 0x80, 0xd5, 0x36, 0x25, 0x76, 0x0a, 0x7b, 0x9f, 0xb8, 0x1c, 0x5d, 0x55, 0x74, 0xd4, 0x73,
+	0x58, 0xd5, 0x51, 0x65, 0xef, 0x27, 0xd4, 0x06, 0x0b, 0x0a, 0x25, 0xb6, 0x61, 0x5b, 0x2e, 0x67,
+	0x19, 0x6f, 0xbf, 0xdb, 0x2a, 0x4d, 0x4f, 0x61, 0xff, 0xc3, 0x72, 0x46, 0x2c, 0x65, 0xa0, 0x5f,
+	0x89, 0x48, 0xff, 0x57, 0x44, 0x65, 0x34, 0xde, 0x09, 0x6c, 0x27, 0xdd, 0xb8, 0x03, 0xc6, 0xeb,
+	0x7e, 0xdf, 0xda, 0x42, 0x80, 0x3a, 0x1b, 0x5c, 0xbe, 0xff, 0x38, 0xb0, 0x34, 0xef, 0x0c, 0x9a,
+	0x6a, 0x63, 0x95, 0xc3, 0x43, 0xa8, 0x53, 0x32, 0x31, 0xf9, 0xf3, 0xe4, 0x31, 0x1e, 0xac, 0x6c,
+	0xc2, 0x14, 0xdc, 0xfd, 0xa5, 0x83, 0xc9, 0x14, 0x84, 0x6f, 0xf2, 0x33, 0x09, 0x3c, 0x2e, 0x3b,
+	0x56, 0x5e, 0x97, 0xe3, 0x6c, 0x82, 0x94, 0x77, 0x5b, 0xf8, 0x16, 0x1a, 0x45, 0x3e, 0x58, 0xa1,
+	0xae, 0x46, 0xed, 0x9c, 0x6c, 0xc4, 0x0a, 0x9d, 0x0b, 0x80, 0x32, 0x1b, 0xac, 0x90, 0xd7, 0x82,
+	0x76, 0xee, 0x6d, 0x06, 0x0b, 0xa9, 0x73, 0xa8, 0xa5, 0xf6, 0xe0, 0xdd, 0x92, 0x58, 0x4d, 0xd8,
+	0x39, 0x5a, 0xab, 0xe7, 0xbd, 0xa7, 0x5a, 0x6f, 0xef, 0x33, 0xe4, 0xe8, 0x6c, 0x38, 0xac, 0xa7,
+	0x8f, 0xf9, 0xc9, 0x9f, 0x01, 0x00, 0x05, 0xbc, 0x5a, 0x36, 0xb6, 0x04, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RegistryClient is the client API for Registry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RegistryClient interface {
+	// Register adds an instance, or replaces the instance at the same address.
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
+	// Heartbeat keeps an instance registered for another TTL. It fails with
+	// NOT_FOUND once the instance expired, which must then register again.
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	// Deregister removes the instance at an address.
+	Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error)
+	// Watch streams the instances of a service: first the instances registered
+	// so far, then the changes.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Registry_WatchClient, error)
+}
+
+type registryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRegistryClient(cc *grpc.ClientConn) RegistryClient {
+	return &registryClient{cc}
+}
+
+func (c *registryClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error) {
+	out := new(RegisterResponse)
+	err := c.cc.Invoke(ctx, "/registry.Registry/Register", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, "/registry.Registry/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error) {
+	out := new(DeregisterResponse)
+	err := c.cc.Invoke(ctx, "/registry.Registry/Deregister", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Registry_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Registry_serviceDesc.Streams[0], "/registry.Registry/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &registryWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Registry_WatchClient interface {
+	Recv() (*WatchResponse, error)
+	grpc.ClientStream
+}
+
+type registryWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *registryWatchClient) Recv() (*WatchResponse, error) {
+	m := new(WatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RegistryServer is the server API for Registry service.
+type RegistryServer interface {
+	// Register adds an instance, or replaces the instance at the same address.
+	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	// Heartbeat keeps an instance registered for another TTL. It fails with
+	// NOT_FOUND once the instance expired, which must then register again.
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	// Deregister removes the instance at an address.
+	Deregister(context.Context, *DeregisterRequest) (*DeregisterResponse, error)
+	// Watch streams the instances of a service: first the instances registered
+	// so far, then the changes.
+	Watch(*WatchRequest, Registry_WatchServer) error
+}
+
+// UnimplementedRegistryServer can be embedded to have forward compatible implementations.
+type UnimplementedRegistryServer struct {
+}
+
+func (*UnimplementedRegistryServer) Register(ctx context.Context, req *RegisterRequest) (*RegisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (*UnimplementedRegistryServer) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (*UnimplementedRegistryServer) Deregister(ctx context.Context, req *DeregisterRequest) (*DeregisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deregister not implemented")
+}
+func (*UnimplementedRegistryServer) Watch(req *WatchRequest, srv Registry_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+
+func RegisterRegistryServer(s *grpc.Server, srv RegistryServer) {
+	s.RegisterService(&_Registry_serviceDesc, srv)
+}
+
+func _Registry_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/registry.Registry/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/registry.Registry/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_Deregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Deregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/registry.Registry/Deregister",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Deregister(ctx, req.(*DeregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistryServer).Watch(m, &registryWatchServer{stream})
+}
+
+type Registry_WatchServer interface {
+	Send(*WatchResponse) error
+	grpc.ServerStream
+}
+
+type registryWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *registryWatchServer) Send(m *WatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Registry_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "registry.Registry",
+	HandlerType: (*RegistryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Registry_Register_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Registry_Heartbeat_Handler,
+		},
+		{
+			MethodName: "Deregister",
+			Handler:    _Registry_Deregister_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Registry_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "registry.proto",
+}
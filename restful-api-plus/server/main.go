@@ -8,18 +8,22 @@ import (
 	"net/http"
 	"context"
 	"flag"
-	"fmt"
 	"log"
 
-	"google.golang.org/grpc/credentials"
-
 	"github.com/golang/protobuf/ptypes/empty"
-	"google.golang.org/grpc/codes"
-
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"github.com/wangy8961/grpc-go-tutorial/healthcheck"
+	"github.com/wangy8961/grpc-go-tutorial/metrics"
+	"github.com/wangy8961/grpc-go-tutorial/restful-api-plus/usererr"
+	"github.com/wangy8961/grpc-go-tutorial/shutdown"
+	"github.com/wangy8961/grpc-go-tutorial/tracing"
 	pb "github.com/wangy8961/grpc-go-tutorial/restful-api-plus/userpb"
+	"github.com/wangy8961/grpc-go-tutorial/validate"
 	swagger "github.com/wangy8961/grpc-go-tutorial/restful-api-plus/go-bindata-assetfs"
 	"github.com/elazarl/go-bindata-assetfs"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 // server is used to implement pb.UserServiceServer.
@@ -39,14 +43,8 @@ func (s *server) Create(ctx context.Context, req *pb.CreateRequest) (*empty.Empt
 	log.Println("--- Creating new user... ---")
 	log.Printf("request received: %v\n", req)
 
+	// The request has been checked against the (validate.rules) in service.proto by the validation interceptor
 	user := req.GetUser()
-	if user.Username == "" {
-		return nil, grpc.Errorf(codes.InvalidArgument, "username cannot be empty")
-	}
-	if user.Password == "" {
-		return nil, grpc.Errorf(codes.InvalidArgument, "password cannot be empty")
-	}
-
 	s.users[user.Username] = *user
 
 	log.Println("--- User created! ---")
@@ -57,14 +55,10 @@ func (s *server) Create(ctx context.Context, req *pb.CreateRequest) (*empty.Empt
 func (s *server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
 	log.Println("--- Getting user... ---")
 
-	if req.Username == "" {
-		return nil, grpc.Errorf(codes.InvalidArgument, "username cannot be empty")
-	}
-
 	u, ok := s.users[req.Username]
 	if !ok {
 		log.Println("--- User not found! ---")
-		return nil, grpc.Errorf(codes.NotFound, "user not found")
+		return nil, usererr.ErrUserNotFound
 	}
 
 	log.Println("--- User found! ---")
@@ -84,6 +78,19 @@ func grpcHandlerFunc(grpcServer *grpc.Server, otherHandler http.Handler) http.Ha
 	})
 }
 
+// route returns the route of a request to the gateway, as declared by the
+// google.api.http options in service.proto.
+func route(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/api/v1/users":
+		return "/api/v1/users"
+	case strings.HasPrefix(r.URL.Path, "/api/v1/users/"):
+		return "/api/v1/users/{username}"
+	default:
+		return "other"
+	}
+}
+
 func serveSwagger(mux *http.ServeMux) {
 	mime.AddExtensionType(".svg", "image/svg+xml")
 
@@ -98,57 +105,110 @@ func serveSwagger(mux *http.ServeMux) {
 }
 
 func main() {
-	host := flag.String("host", "localhost", "gRPC Server Name or IP")
-	port := flag.Int("port", 50051, "the port to serve on")
-	certFile := flag.String("certfile", "server.crt", "Server certificate")
-	keyFile := flag.String("keyfile", "server.key", "Server private key")
-	caCertFile := flag.String("cacert", "cacert.pem", "CA root certificate")
+	cfg := bootstrap.DefaultConfig()
+	// gRPC 服务和反向代理服务共同监听的地址
+	cfg.Server.Address = "localhost:50051"
+	cfg.Server.TLS.CertFile = "server.crt"
+	cfg.Server.TLS.KeyFile = "server.key"
+	cfg.Server.Interceptors = bootstrap.InterceptorsConfig{Tracing: true, Logging: true, Metrics: true, Recovery: true}
+	cfg.Client.TLS.CAFile = "cacert.pem" // of the gateway, which calls the gRPC server
+	cfg.Admin.Address = ":9090"
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
+	caCertFile := flag.String("cacert", "", "CA root certificate of the gateway, overrides client.tls.ca_file of the config (default cacert.pem)")
 	swaggerJSON := flag.String("swagger", "../userpb/service.swagger.json", "Swagger JSON file")
 	flag.Parse()
 
-	// gRPC 服务和反向代理服务共同监听的地址
-	endpoint := fmt.Sprintf("%s:%d", *host, *port)
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if *caCertFile != "" {
+		cfg.Client.TLS.CAFile = *caCertFile
+	}
+	endpoint := cfg.Server.Address
+
+	// REST latencies (httpMetrics) can be compared with the gRPC ones of the
+	// gateway (gwMetrics) and of the gRPC server (s.Metrics)
+	gwMetrics := metrics.NewClient()
+	httpMetrics := metrics.NewHTTP(route)
 
 	// gRPC 服务端
-	creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
+	// One trace per REST request: the HTTP span of the gateway, the client span
+	// of its gRPC call and the server span of the User service
+	s, err := bootstrap.NewServer(cfg,
+		bootstrap.WithCollectors(gwMetrics, httpMetrics),
+		bootstrap.WithUnaryInterceptors(
+			// Map the usererr errors returned by handlers to gRPC status codes
+			errmap.UnaryServerInterceptor(),
+			// Reject requests that violate the (validate.rules) declared in service.proto
+			validate.UnaryServerInterceptor(),
+		),
+	) // Create an instance of the gRPC server
 	if err != nil {
-		log.Fatalf("failed to load certificates: %v", err)
+		log.Fatalf("failed to create server: %v", err)
+	}
+	defer s.Close()
+	pb.RegisterUserServiceServer(s.Server, NewServer()) // Register our service implementation with the gRPC server
+	health := healthcheck.Register(s.Server)            // Report the serving status of the User service, NOT_SERVING once shutting down
+	reflection.Register(s.Server)                       // Describe the services to dynamic clients, such as grpccli
+	// Serve the metrics, pprof and channelz on a separate admin port
+	if err := s.ServeAdmin(health); err != nil {
+		log.Fatalf("failed to serve admin endpoints: %v", err)
 	}
-	grpcServer := grpc.NewServer(grpc.Creds(creds)) // Create an instance of the gRPC server
-	pb.RegisterUserServiceServer(grpcServer, NewServer()) // Register our service implementation with the gRPC server
-	
+
 	// grpc-gateway 反向代理
 	// 它相当于 gRPC 客户端，负责将 RESTful API 的客户端的请求转发给 gRPC 服务端
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	gwCreds, gwErr := credentials.NewClientTLSFromFile(*caCertFile, "")
+	gwConfig := cfg.Client
+	gwConfig.Address = endpoint
+	gwConn, gwErr := bootstrap.Dial(&gwConfig,
+		grpc.WithChainUnaryInterceptor(
+			s.Tracer.UnaryClientInterceptor(),
+			gwMetrics.UnaryClientInterceptor(),
+		),
+	)
 	if gwErr != nil {
-		log.Fatalf("failed to load CA root certificate: %v", gwErr)
+		log.Fatalf("did not connect: %v", gwErr)
 	}
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(gwCreds)}
-	gwMux := runtime.NewServeMux()
-	gwErr = pb.RegisterUserServiceHandlerFromEndpoint(ctx, gwMux, endpoint, opts)
+	defer gwConn.Close()
+	// Forward the traceparent header set by tracer.HTTPHandler in the metadata of the gRPC calls
+	gwMux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(tracing.IncomingHeaderMatcher))
+	gwErr = pb.RegisterUserServiceHandler(ctx, gwMux, gwConn)
 	if gwErr != nil {
 		log.Fatalf("failed to register grpc-gateway: %v", gwErr)
 	}
-	
+	// The readiness probe checks the health of the upstream gRPC server
+	healthConn, gwErr := bootstrap.Dial(&gwConfig)
+	if gwErr != nil {
+		log.Fatalf("failed to dial health service: %v", gwErr)
+	}
+	defer healthConn.Close()
+
 	// 指定 gRPC-gateway 反向代理所有的 HTTP2 服务的路由
 	mux := http.NewServeMux()
-	mux.Handle("/", gwMux)
+	mux.Handle("/", httpMetrics.Handler(s.Tracer.HTTPHandler(route, gwMux)))
 	// Swagger
 	mux.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 		// io.Copy(w, strings.NewReader(*swaggerJSON))
 		http.ServeFile(w, r, *swaggerJSON)
 	})
 	serveSwagger(mux)
+	// Health probes of the orchestrator
+	mux.Handle("/healthz", healthcheck.LiveHandler())
+	mux.Handle("/readyz", healthcheck.ReadyHandler(healthConn, "user.UserService"))
 	// 启动 HTTP2 服务器（需要指定服务器的数字证书和私钥）
 	srv := &http.Server{
-        Addr:         endpoint,
-        Handler:      grpcHandlerFunc(grpcServer, mux),  // HTTP2 服务器接收到任何请求后，再由 grpcHandlerFunc 根据请求的协议判断是直接调用 gRPC 服务端还是由 gRPC-gateway 继续反向代理
+		Addr:    endpoint,
+		Handler: grpcHandlerFunc(s.Server, mux), // HTTP2 服务器接收到任何请求后，再由 grpcHandlerFunc 根据请求的协议判断是直接调用 gRPC 服务端还是由 gRPC-gateway 继续反向代理
 	}
-	
+
+	// Report NOT_SERVING, so that /readyz fails, then stop serving on SIGINT or SIGTERM
+	stopped := shutdown.HTTP(srv, health, cfg.Server.ShutdownGrace)
+
 	log.Printf("gRPC server and gRPC-gateway listening at %v\n", endpoint)
-    if httpErr := srv.ListenAndServeTLS(*certFile, *keyFile); httpErr != nil {
+	if httpErr := srv.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile); httpErr != nil && httpErr != http.ErrServerClosed {
 		log.Fatalf("failed to listen and serve: %v", httpErr)
-    }
+	}
+	<-stopped // Wait for the requests in flight
 }
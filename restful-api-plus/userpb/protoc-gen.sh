@@ -3,6 +3,7 @@
 # 1. Generate gRPC Golang stub
 protoc -I/usr/local/include -I. \
   -I$GOPATH/src \
+  -I../.. \
   -I$GOPATH/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis \
   --go_out=plugins=grpc:. \
   service.proto
@@ -10,6 +11,7 @@ protoc -I/usr/local/include -I. \
 # 2. Generate a reverse-proxy server which translates a RESTful JSON API into gRPC
 protoc -I/usr/local/include -I. \
   -I$GOPATH/src \
+  -I../.. \
   -I$GOPATH/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis \
   --grpc-gateway_out=logtostderr=true:. \
   service.proto
@@ -17,6 +19,7 @@ protoc -I/usr/local/include -I. \
 # 3. (Optional) Generate swagger definitions
 protoc -I/usr/local/include -I. \
   -I$GOPATH/src \
+  -I../.. \
   -I$GOPATH/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis \
   --swagger_out=logtostderr=true:. \
   service.proto
\ No newline at end of file
@@ -8,6 +8,8 @@ import (
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
 	empty "github.com/golang/protobuf/ptypes/empty"
+	_ "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
+	_ "github.com/wangy8961/grpc-go-tutorial/validate"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
@@ -204,26 +206,40 @@ func init() {
 func init() { proto.RegisterFile("service.proto", fileDescriptor_a0b84a42fa06f626) }
 
 var fileDescriptor_a0b84a42fa06f626 = []byte{
-	// 292 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x50, 0xc1, 0x4a, 0x03, 0x31,
-	0x10, 0x65, 0x6b, 0x29, 0x75, 0x96, 0x05, 0x8d, 0x20, 0x4b, 0x14, 0x29, 0x39, 0x15, 0xc1, 0x04,
-	0xeb, 0xcd, 0x83, 0x07, 0x45, 0x7a, 0x11, 0x84, 0x15, 0x2f, 0xde, 0xb2, 0x3a, 0x96, 0x05, 0x9b,
-	0xc4, 0x24, 0x5b, 0x11, 0xf1, 0xe2, 0x2f, 0xf8, 0x1b, 0xfe, 0x8d, 0xbf, 0xe0, 0x87, 0x48, 0x12,
-	0x57, 0xed, 0x45, 0x8f, 0x6f, 0xde, 0x9b, 0x37, 0x6f, 0x1e, 0x14, 0x0e, 0xed, 0xa2, 0xb9, 0x46,
-	0x6e, 0xac, 0xf6, 0x9a, 0xf4, 0x5b, 0x87, 0x96, 0x6e, 0xcf, 0xb4, 0x9e, 0xdd, 0xa1, 0x90, 0xa6,
-	0x11, 0x52, 0x29, 0xed, 0xa5, 0x6f, 0xb4, 0x72, 0x49, 0x43, 0xb7, 0xbe, 0xd8, 0x88, 0xea, 0xf6,
-	0x56, 0xe0, 0xdc, 0xf8, 0xc7, 0x44, 0xb2, 0x23, 0xe8, 0x5f, 0x3a, 0xb4, 0x84, 0xc2, 0x30, 0x58,
-	0x29, 0x39, 0xc7, 0x32, 0x1b, 0x65, 0xe3, 0xd5, 0xea, 0x1b, 0x07, 0xce, 0x48, 0xe7, 0x1e, 0xb4,
-	0xbd, 0x29, 0x7b, 0x89, 0xeb, 0x30, 0x13, 0x50, 0x9c, 0x58, 0x94, 0x1e, 0x2b, 0xbc, 0x6f, 0xd1,
-	0x79, 0xb2, 0x03, 0x31, 0x53, 0x34, 0xc9, 0x27, 0xc0, 0x03, 0xe0, 0xe1, 0x44, 0x15, 0xe7, 0x6c,
-	0x0c, 0x30, 0x45, 0xdf, 0xa9, 0xff, 0x38, 0xcb, 0xf6, 0x20, 0x8f, 0x4a, 0x67, 0xb4, 0x72, 0xf8,
-	0x9f, 0xf1, 0xe4, 0x2d, 0x83, 0x3c, 0xc0, 0x8b, 0x54, 0x10, 0x39, 0x87, 0x41, 0x4a, 0x46, 0x36,
-	0x92, 0x76, 0x29, 0x27, 0xdd, 0xe4, 0xa9, 0x16, 0xde, 0xd5, 0xc2, 0x4f, 0x43, 0x2d, 0xac, 0x7c,
-	0x79, 0xff, 0x78, 0xed, 0x11, 0x56, 0xc4, 0x36, 0x17, 0xfb, 0x22, 0xec, 0xba, 0xc3, 0x6c, 0x97,
-	0x9c, 0xc1, 0xca, 0x14, 0x3d, 0x59, 0x4b, 0x6e, 0x3f, 0x4f, 0xd0, 0xf5, 0x5f, 0x93, 0x14, 0x96,
-	0x8d, 0xa2, 0x0b, 0x25, 0xe5, 0x92, 0x8b, 0x78, 0xea, 0x9e, 0x7b, 0x3e, 0x1e, 0x5e, 0x0d, 0x02,
-	0x30, 0x75, 0x3d, 0x88, 0x09, 0x0e, 0x3e, 0x03, 0x00, 0x00, 0xff, 0xff, 0x06, 0x04, 0x7d, 0xf8,
-	0xdb, 0x01, 0x00, 0x00,
+	// 528 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xc1, 0x8e, 0xd3, 0x3c,
+	0x10, 0x56, 0xd2, 0xaa, 0x7f, 0x7e, 0x97, 0x4a, 0x5d, 0x23, 0x41, 0x08, 0x08, 0xa2, 0xb0, 0x42,
+	0xa8, 0x22, 0x71, 0xdb, 0xad, 0x16, 0xb6, 0x1c, 0xd8, 0x2d, 0x42, 0x7b, 0x41, 0x42, 0x0a, 0x70,
+	0x59, 0xb4, 0x20, 0x37, 0x1d, 0xd2, 0xa0, 0xd6, 0x36, 0xb6, 0xd3, 0xb2, 0x20, 0x2e, 0x3c, 0x02,
+	0xdc, 0x78, 0x06, 0x9e, 0xa4, 0x27, 0x24, 0xae, 0x1c, 0x79, 0x83, 0xbe, 0x00, 0x8a, 0xd3, 0x16,
+	0x96, 0x85, 0x93, 0x3d, 0xf3, 0xcd, 0x7c, 0xf3, 0x8d, 0x3f, 0xa3, 0x86, 0x02, 0x39, 0xcb, 0x12,
+	0x88, 0x84, 0xe4, 0x9a, 0xe3, 0x6a, 0xae, 0x40, 0x7a, 0x57, 0x52, 0xce, 0xd3, 0x09, 0x10, 0x2a,
+	0x32, 0x42, 0x19, 0xe3, 0x9a, 0xea, 0x8c, 0x33, 0x55, 0xd6, 0x78, 0x97, 0x57, 0xa8, 0x89, 0x86,
+	0xf9, 0x4b, 0x02, 0x53, 0xa1, 0x4f, 0x56, 0xe0, 0x2d, 0x73, 0x24, 0x61, 0x0a, 0x2c, 0x54, 0x73,
+	0x9a, 0xa6, 0x20, 0x09, 0x17, 0xa6, 0xfd, 0x2f, 0x54, 0x17, 0x67, 0x74, 0x92, 0x8d, 0xa8, 0x06,
+	0xb2, 0xbe, 0x94, 0x40, 0x30, 0x45, 0xd5, 0xa7, 0x0a, 0x24, 0xbe, 0x87, 0x9c, 0x42, 0x11, 0xa3,
+	0x53, 0x70, 0x2d, 0xdf, 0xba, 0xf9, 0xff, 0xe0, 0xfa, 0x62, 0xe9, 0x5e, 0x73, 0x2c, 0xd7, 0x0f,
+	0x2e, 0x3d, 0x7f, 0x76, 0x10, 0x1e, 0xd1, 0xf0, 0xed, 0xf1, 0xea, 0x6c, 0x87, 0x7b, 0x2f, 0xa2,
+	0xf0, 0xb8, 0xb5, 0x1d, 0x6f, 0x9a, 0xf0, 0x36, 0x72, 0x04, 0x55, 0x6a, 0xce, 0xe5, 0xc8, 0xb5,
+	0x0d, 0x81, 0xb3, 0x58, 0xba, 0x55, 0xc7, 0x72, 0xf7, 0xe3, 0x0d, 0x12, 0xdc, 0x46, 0x8d, 0xfb,
+	0x12, 0xa8, 0x86, 0x18, 0x5e, 0xe7, 0xa0, 0x34, 0xbe, 0x81, 0xcc, 0x4b, 0x98, 0x99, 0xf5, 0x2e,
+	0x8a, 0x8a, 0x20, 0x2a, 0x14, 0x0d, 0x6a, 0x8b, 0xa5, 0x6b, 0x3b, 0x56, 0x6c, 0xf0, 0xa0, 0x8d,
+	0xd0, 0x21, 0xe8, 0x75, 0x57, 0x70, 0x46, 0xed, 0xba, 0x7a, 0x93, 0x0f, 0x42, 0x54, 0x37, 0x1d,
+	0x4a, 0x70, 0xa6, 0x00, 0x5f, 0xfd, 0xd7, 0xa0, 0x72, 0x40, 0xf7, 0x8b, 0x85, 0xea, 0x45, 0xf8,
+	0xb8, 0xb4, 0x09, 0x3f, 0x42, 0xb5, 0x52, 0x29, 0x3e, 0x5f, 0xd6, 0x9e, 0xd2, 0xed, 0x5d, 0x88,
+	0x4a, 0x73, 0xa2, 0xb5, 0x39, 0xd1, 0x83, 0xc2, 0x9c, 0xc0, 0xfd, 0xf0, 0xed, 0xc7, 0x27, 0x1b,
+	0x07, 0x0d, 0xe3, 0xe9, 0xac, 0x43, 0x8a, 0x5e, 0xd5, 0xb7, 0x5a, 0xf8, 0x21, 0xaa, 0x1c, 0x82,
+	0xc6, 0xcd, 0x92, 0xed, 0xd7, 0x32, 0xde, 0xd6, 0x6f, 0x99, 0x52, 0x6c, 0xe0, 0x1b, 0x16, 0x0f,
+	0xbb, 0xa7, 0x58, 0xc8, 0xbb, 0xf5, 0x72, 0xef, 0x07, 0xdf, 0xad, 0x8f, 0x07, 0x5f, 0x2d, 0x9c,
+	0xa1, 0x73, 0x85, 0x68, 0x7f, 0xf5, 0xb9, 0x82, 0x27, 0xa8, 0x99, 0x4a, 0x91, 0x84, 0x29, 0x0f,
+	0x75, 0xae, 0xb9, 0xcc, 0xe8, 0x04, 0x87, 0x63, 0xad, 0x85, 0xea, 0x13, 0x92, 0x66, 0x7a, 0x9c,
+	0x0f, 0xa3, 0x84, 0x4f, 0xc9, 0x9c, 0xb2, 0xf4, 0xe4, 0xce, 0xde, 0x6e, 0x87, 0xfc, 0x59, 0xee,
+	0x6d, 0x6d, 0xb0, 0xfd, 0xce, 0xee, 0x4e, 0x51, 0xdd, 0xad, 0x74, 0xa2, 0x76, 0xcb, 0xb6, 0xec,
+	0x6e, 0x93, 0x0a, 0x31, 0xc9, 0x12, 0xf3, 0xb5, 0xc8, 0x2b, 0xc5, 0x59, 0xff, 0x4c, 0x26, 0xbe,
+	0x8b, 0x2a, 0xbd, 0x76, 0x0f, 0xf7, 0x50, 0x2b, 0x06, 0x9d, 0x4b, 0x06, 0x23, 0x7f, 0x3e, 0x06,
+	0xe6, 0xeb, 0x31, 0xf8, 0x12, 0x14, 0xcf, 0x65, 0x02, 0xfe, 0x88, 0x83, 0xf2, 0x19, 0xd7, 0x3e,
+	0xbc, 0xc9, 0x94, 0x8e, 0x70, 0x0d, 0x55, 0x3f, 0xdb, 0xd6, 0x7f, 0x47, 0xb5, 0x62, 0x43, 0x31,
+	0x1c, 0xd6, 0xcc, 0xb3, 0xee, 0xfc, 0x1c, 0x00, 0xf7, 0xcf, 0x7a, 0xe7, 0x36, 0x03, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
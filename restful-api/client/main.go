@@ -2,13 +2,16 @@
 package main
 
 import (
-	"time"
 	"context"
+	"errors"
 	"flag"
 	"log"
 
-	"google.golang.org/grpc/credentials"
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"github.com/wangy8961/grpc-go-tutorial/hedging"
+	"github.com/wangy8961/grpc-go-tutorial/restful-api/usererr"
 	pb "github.com/wangy8961/grpc-go-tutorial/restful-api/userpb"
 	"google.golang.org/grpc"
 )
@@ -16,10 +19,7 @@ import (
 func createUserCall(client pb.UserServiceClient, username, password string) {
 	log.Println("--- gRPC Create RPC Call ---")
 
-	// 设置 10 秒超时时长，可参考 https://madmalls.com/blog/post/grpc-deadline/#21-contextwithtimeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
+	// 超时时长由 service config 设置，见 service_config.json
 	// 调用 Create RPC
 	req := &pb.CreateRequest{
 		User: &pb.User{
@@ -27,7 +27,7 @@ func createUserCall(client pb.UserServiceClient, username, password string) {
 			Password: password,
 		},
 	}
-	resp, err := client.Create(ctx, req)
+	resp, err := client.Create(context.Background(), req)
 	if err != nil {
 		log.Fatalf("failed to call Create RPC: %v", err)
 	}
@@ -39,15 +39,17 @@ func createUserCall(client pb.UserServiceClient, username, password string) {
 func getUserCall(client pb.UserServiceClient, username string) {
 	log.Println("--- gRPC Get RPC Call ---")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	// 调用 Get RPC
 	req := &pb.GetRequest{
 		Username: username,
 	}
-	resp, err := client.Get(ctx, req)
+	resp, err := client.Get(context.Background(), req)
 	if err != nil {
+		// The errmap client interceptor turns the status back into a usererr error
+		if errors.Is(err, usererr.ErrUserNotFound) {
+			log.Printf("user %q does not exist", username)
+			return
+		}
 		log.Fatalf("failed to call Get RPC: %v", err)
 	}
 
@@ -56,17 +58,31 @@ func getUserCall(client pb.UserServiceClient, username string) {
 }
 
 func main() {
-	addr := flag.String("addr", "localhost:50051", "the address to connect to")
-	certFile := flag.String("cacert", "cacert.pem", "CA root certificate")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Client.TLS.CAFile = "cacert.pem"
+	cfg.Client.ServiceConfigFile = bootstrap.SourcePath("service_config.json") // 各方法的超时时长
+	flags := bootstrap.ClientFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	creds, err := credentials.NewClientTLSFromFile(*certFile, "")
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to load CA root certificate: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
 
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(creds)) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the server address and port number to grpc.Dial()
+	opts := []grpc.DialOption{
+		// Map the errors of the User service back to the usererr errors, and
+		// hedge the slow Get calls, at most 10% more requests. Create is not
+		// idempotent and must not be hedged.
+		grpc.WithChainUnaryInterceptor(
+			errmap.UnaryClientInterceptor(),
+			hedging.UnaryClientInterceptor(
+				hedging.WithMethods("/user.UserService/Get"),
+				hedging.WithBudget(hedging.NewBudget(0.1, 10)),
+			),
+		),
+	}
+	conn, err := bootstrap.Dial(&cfg.Client, opts...) // To call service methods, we first need to create a gRPC channel to communicate with the server. We create this by passing the config of the client, with the server address and port number, to bootstrap.Dial()
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -2,7 +2,6 @@
 package main
 
 import (
-	"google.golang.org/grpc/credentials"
 	"context"
 	"flag"
 	"log"
@@ -11,32 +10,41 @@ import (
 	"github.com/golang/glog"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
-	"google.golang.org/grpc"
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
 
 	gw "github.com/wangy8961/grpc-go-tutorial/restful-api/userpb"
 )
 
-var (
-	// gRPC server 监听的地址
-	endpoint = flag.String("endpoint", "localhost:50051", "endpoint of User service")
-	certFile = flag.String("cacert", "cacert.pem", "CA root certificate")
-)
+// flags are the flags of the connection to the User service: -addr is the
+// address the gRPC server listens on (gRPC server 监听的地址)
+var flags = bootstrap.ClientFlags(flag.CommandLine, defaultConfig())
+
+func defaultConfig() *bootstrap.Config {
+	c := bootstrap.DefaultConfig()
+	c.Client.TLS.CAFile = "cacert.pem"
+	return c
+}
 
 func run() error {
+	cfg, err := flags.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	mux := runtime.NewServeMux()
 
-	creds, err := credentials.NewClientTLSFromFile(*certFile, "")
+	conn, err := bootstrap.Dial(&cfg.Client)
 	if err != nil {
-		log.Fatalf("failed to load CA root certificate: %v", err)
+		log.Fatalf("did not connect: %v", err)
 	}
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	defer conn.Close()
 
-	err = gw.RegisterUserServiceHandlerFromEndpoint(ctx, mux, *endpoint, opts)
+	err = gw.RegisterUserServiceHandler(ctx, mux, conn)
 	if err != nil {
-		log.Fatalf("failed to automatically dials to endpoint: %v", err)
+		log.Fatalf("failed to register grpc-gateway: %v", err)
 	}
 
 	// RESTful API 反向代理所监听的地址，并设置 HTTP 服务器的路由使用 mux
@@ -4,17 +4,14 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"log"
-	"net"
-
-	"google.golang.org/grpc/credentials"
 
 	"github.com/golang/protobuf/ptypes/empty"
-	"google.golang.org/grpc/codes"
-
+	"github.com/wangy8961/grpc-go-tutorial/bootstrap"
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"github.com/wangy8961/grpc-go-tutorial/restful-api/usererr"
 	pb "github.com/wangy8961/grpc-go-tutorial/restful-api/userpb"
-	"google.golang.org/grpc"
+	"github.com/wangy8961/grpc-go-tutorial/validate"
 )
 
 // server is used to implement pb.UserServiceServer.
@@ -34,14 +31,8 @@ func (s *server) Create(ctx context.Context, req *pb.CreateRequest) (*empty.Empt
 	log.Println("--- Creating new user... ---")
 	log.Printf("request received: %v\n", req)
 
+	// The request has been checked against the (validate.rules) in service.proto by the validation interceptor
 	user := req.GetUser()
-	if user.Username == "" {
-		return nil, grpc.Errorf(codes.InvalidArgument, "username cannot be empty")
-	}
-	if user.Password == "" {
-		return nil, grpc.Errorf(codes.InvalidArgument, "password cannot be empty")
-	}
-
 	s.users[user.Username] = *user
 
 	log.Println("--- User created! ---")
@@ -52,13 +43,9 @@ func (s *server) Create(ctx context.Context, req *pb.CreateRequest) (*empty.Empt
 func (s *server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
 	log.Println("--- Getting user... ---")
 
-	if req.Username == "" {
-		return nil, grpc.Errorf(codes.InvalidArgument, "username cannot be empty")
-	}
-
 	u, exists := s.users[req.Username]
 	if !exists {
-		return nil, grpc.Errorf(codes.NotFound, "user not found")
+		return nil, usererr.ErrUserNotFound
 	}
 
 	log.Println("--- User found! ---")
@@ -66,26 +53,34 @@ func (s *server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse,
 }
 
 func main() {
-	port := flag.Int("port", 50051, "the port to serve on")
-	certFile := flag.String("certfile", "server.crt", "Server certificate")
-	keyFile := flag.String("keyfile", "server.key", "Server private key")
+	cfg := bootstrap.DefaultConfig()
+	cfg.Server.TLS.CertFile = "server.crt"
+	cfg.Server.TLS.KeyFile = "server.key"
+	cfg.Admin.Address = ":9090"
+	// Log one JSON line per call, and turn the panics of the handlers, e.g. on a nil user, into Internal errors
+	cfg.Server.Interceptors = bootstrap.InterceptorsConfig{Logging: true, Metrics: true, Recovery: true}
+	flags := bootstrap.ServerFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port)) // Specify the port we want to use to listen for client requests
+	cfg, err := flags.Load()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
-	fmt.Printf("server listening at %v\n", lis.Addr())
 
-	creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
+	s, err := bootstrap.NewServer(cfg,
+		bootstrap.WithUnaryInterceptors(
+			// Map the usererr errors returned by handlers to gRPC status codes
+			errmap.UnaryServerInterceptor(),
+			// Reject requests that violate the (validate.rules) declared in service.proto
+			validate.UnaryServerInterceptor(),
+		),
+	) // Create an instance of the gRPC server
 	if err != nil {
-		log.Fatalf("failed to load certificates: %v", err)
+		log.Fatalf("failed to create server: %v", err)
 	}
 
-	s := grpc.NewServer(grpc.Creds(creds)) // Create an instance of the gRPC server
-
-	pb.RegisterUserServiceServer(s, NewServer()) // Register our service implementation with the gRPC server
-	if err := s.Serve(lis); err != nil {         // Call Serve() on the server with our port details to do a blocking wait until the process is killed or Stop() is called.
+	pb.RegisterUserServiceServer(s.Server, NewServer()) // Register our service implementation with the gRPC server
+	if err := s.Serve(); err != nil {                   // Call Serve() on the server to listen on the configured address and do a blocking wait until the process is killed or Stop() is called.
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
@@ -0,0 +1,23 @@
+// Package usererr declares the errors of the User service.
+package usererr
+
+import (
+	"errors"
+
+	"github.com/wangy8961/grpc-go-tutorial/errmap"
+	"google.golang.org/grpc/codes"
+)
+
+// Domain is the ErrorInfo domain of the User service errors.
+const Domain = "user.madmalls.com"
+
+// ErrUserNotFound is returned by Get for unknown usernames.
+var ErrUserNotFound = errors.New("user not found")
+
+func init() {
+	errmap.Register(ErrUserNotFound, errmap.Mapping{
+		Code:   codes.NotFound,
+		Domain: Domain,
+		Reason: "USER_NOT_FOUND",
+	})
+}
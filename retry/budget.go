@@ -0,0 +1,47 @@
+package retry
+
+import "sync"
+
+// Budget limits the retries of a client in the way of the gRPC retry throttling
+// policy: it holds up to maxTokens tokens, every failed attempt takes one token,
+// every successful call gives back tokenRatio tokens, and retries are only
+// allowed while more than half of the tokens are left. This prevents retries
+// from multiplying the load of a server that is already overloaded.
+type Budget struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	tokenRatio float64
+}
+
+// NewBudget returns a full Budget, e.g. NewBudget(10, 0.1).
+func NewBudget(maxTokens, tokenRatio float64) *Budget {
+	return &Budget{tokens: maxTokens, maxTokens: maxTokens, tokenRatio: tokenRatio}
+}
+
+// onSuccess gives tokenRatio tokens back.
+func (b *Budget) onSuccess() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.tokenRatio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// onFailure takes a token and reports whether a retry is still allowed.
+func (b *Budget) onFailure() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens--
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+	return b.tokens > b.maxTokens/2
+}
@@ -0,0 +1,95 @@
+// Package retry provides client interceptors that retry failed RPCs with
+// exponential backoff and jitter, within a retry budget and the deadline of the
+// call, and that honor the RetryInfo pushback sent by servers.
+package retry
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultCodes are the status codes retried by default. They indicate that the
+// server did not process the request, so retrying is safe.
+var DefaultCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+
+type options struct {
+	max               int
+	codes             []codes.Code
+	initialBackoff    time.Duration
+	maxBackoff        time.Duration
+	multiplier        float64
+	jitter            float64
+	perAttemptTimeout time.Duration
+	budget            *Budget
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		max:            3,
+		codes:          DefaultCodes,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     5 * time.Second,
+		multiplier:     2,
+		jitter:         0.2,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) retryable(c codes.Code) bool {
+	for _, rc := range o.codes {
+		if rc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Option configures the retry interceptors.
+type Option func(*options)
+
+// WithMax sets the maximum number of retries after the first attempt (3 by default).
+func WithMax(n int) Option {
+	return func(o *options) {
+		o.max = n
+	}
+}
+
+// WithCodes sets the status codes that are retried (DefaultCodes by default).
+func WithCodes(c ...codes.Code) Option {
+	return func(o *options) {
+		o.codes = c
+	}
+}
+
+// WithBackoff sets the exponential backoff between attempts: the n-th retry
+// waits initial*multiplier^(n-1), capped at max and randomized by ±jitter
+// (a fraction between 0 and 1). By default 100ms, 5s, 2 and 0.2.
+func WithBackoff(initial, max time.Duration, multiplier, jitter float64) Option {
+	return func(o *options) {
+		o.initialBackoff = initial
+		o.maxBackoff = max
+		o.multiplier = multiplier
+		o.jitter = jitter
+	}
+}
+
+// WithPerAttemptTimeout bounds every attempt of a unary call. An attempt never
+// outlives the deadline of the call. Attempts that time out while the call
+// still has time left are retried. Disabled by default.
+func WithPerAttemptTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.perAttemptTimeout = d
+	}
+}
+
+// WithBudget shares a retry budget between calls, so that retries stop when
+// too many calls fail. No budget is used by default.
+func WithBudget(b *Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/wangy8961/grpc-go-tutorial/statusdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns a client-side unary interceptor that retries
+// the calls failing with one of the retryable codes.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		for attempt := 1; ; attempt++ {
+			attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+			if o.perAttemptTimeout > 0 {
+				// context.WithTimeout never extends the deadline of ctx
+				attemptCtx, cancel = context.WithTimeout(ctx, o.perAttemptTimeout)
+			}
+			err := invoker(attemptCtx, method, req, reply, cc, callOpts...)
+			cancel()
+			if err == nil {
+				o.budget.onSuccess()
+				return nil
+			}
+
+			// An attempt that ran out of its own time while the call still has time left is retried
+			attemptTimedOut := status.Code(err) == codes.DeadlineExceeded && ctx.Err() == nil
+			delay, ok := o.delay(ctx, attempt, err, attemptTimedOut)
+			if !ok {
+				return err
+			}
+			log.Printf("retry: attempt %d of %s failed with %v, retrying in %v", attempt, method, status.Code(err), delay)
+			if !sleep(ctx, delay) {
+				return err
+			}
+		}
+	}
+}
+
+// delay decides whether the failed attempt (starting at 1) is retried, and how
+// long to wait before the next one.
+func (o *options) delay(ctx context.Context, attempt int, err error, force bool) (time.Duration, bool) {
+	if attempt > o.max || ctx.Err() != nil {
+		return 0, false
+	}
+	if !force && !o.retryable(status.Code(err)) {
+		return 0, false
+	}
+	if !o.budget.onFailure() {
+		log.Printf("retry: budget exhausted, not retrying")
+		return 0, false
+	}
+
+	// The server knows better than us when it will be able to serve the call again
+	d, pushback := statusdetails.RetryDelay(err)
+	if !pushback {
+		d = o.backoff(attempt)
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		// The next attempt would start after the deadline of the call
+		return 0, false
+	}
+	return d, true
+}
+
+// backoff returns how long to wait before the n-th retry (n >= 1).
+func (o *options) backoff(n int) time.Duration {
+	d := float64(o.initialBackoff)
+	for i := 1; i < n && d < float64(o.maxBackoff); i++ {
+		d *= o.multiplier
+	}
+	if d > float64(o.maxBackoff) {
+		d = float64(o.maxBackoff)
+	}
+	// Randomize by ±jitter so that clients failing together do not retry together
+	d *= 1 + o.jitter*(2*rand.Float64()-1)
+	return time.Duration(d)
+}
+
+// sleep waits for d, and returns false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
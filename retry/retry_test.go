@@ -0,0 +1,331 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// errHang makes a call of flakyServer hang until it is canceled.
+var errHang = errors.New("hang")
+
+// flakyServer fails its calls with the errors of errs, in order, and succeeds
+// once they are used up. A nil error lets the call through.
+type flakyServer struct {
+	echoserver.Server
+
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (s *flakyServer) next() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func (s *flakyServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *flakyServer) UnaryEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	if err := s.next(); err == errHang {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	} else if err != nil {
+		return nil, err
+	}
+	return &pb.EchoResponse{Message: req.GetMessage()}, nil
+}
+
+// ServerStreamingEcho fails before the first response with the next error, or
+// with Unavailable after the first response if the message is "fail after first".
+func (s *flakyServer) ServerStreamingEcho(req *pb.EchoRequest, stream pb.Echo_ServerStreamingEchoServer) error {
+	err := s.next()
+	if req.GetMessage() != "fail after first" {
+		if err != nil {
+			return err
+		}
+		return stream.Send(&pb.EchoResponse{Message: req.GetMessage()})
+	}
+	if err := stream.Send(&pb.EchoResponse{Message: req.GetMessage()}); err != nil {
+		return err
+	}
+	return status.Error(codes.Unavailable, "failure after the first response")
+}
+
+// dial starts srv over an in-memory listener and returns a client using the
+// retry interceptors built with opts, and a function stopping both.
+func dial(t *testing.T, srv *flakyServer, opts ...Option) (pb.EchoClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	pb.RegisterEchoServer(s, srv)
+	go s.Serve(lis)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(opts...)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(opts...)),
+	)
+	if err != nil {
+		s.Stop()
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return pb.NewEchoClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func unavailable() error {
+	return status.Error(codes.Unavailable, "simulated failure")
+}
+
+// pushback returns a ResourceExhausted error asking to retry in d.
+func pushback(t *testing.T, d time.Duration) error {
+	st, err := status.New(codes.ResourceExhausted, "simulated overload").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(d),
+	})
+	if err != nil {
+		t.Fatalf("failed to add RetryInfo: %v", err)
+	}
+	return st.Err()
+}
+
+func TestBackoff(t *testing.T) {
+	o := newOptions([]Option{WithBackoff(10*time.Millisecond, 50*time.Millisecond, 2, 0)})
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := o.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	o = newOptions([]Option{WithBackoff(100*time.Millisecond, time.Second, 2, 0.2)})
+	for i := 0; i < 100; i++ {
+		if got := o.backoff(1); got < 80*time.Millisecond || got > 120*time.Millisecond {
+			t.Fatalf("backoff(1) = %v with a jitter of 0.2, want within [80ms, 120ms]", got)
+		}
+	}
+}
+
+func TestUnaryRetriesUntilSuccess(t *testing.T) {
+	srv := &flakyServer{errs: []error{unavailable(), unavailable()}}
+	c, stop := dial(t, srv, WithBackoff(20*time.Millisecond, time.Second, 2, 0))
+	defer stop()
+
+	start := time.Now()
+	res, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"})
+	if err != nil {
+		t.Fatalf("UnaryEcho() failed: %v", err)
+	}
+	if res.GetMessage() != "hello" {
+		t.Errorf("UnaryEcho() = %q, want %q", res.GetMessage(), "hello")
+	}
+	if got := srv.count(); got != 3 {
+		t.Errorf("server got %d calls, want 3", got)
+	}
+	// Backoffs of 20ms and 40ms
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("call took %v, want at least 60ms of backoff", elapsed)
+	}
+}
+
+func TestUnaryMaxRetries(t *testing.T) {
+	srv := &flakyServer{errs: []error{unavailable(), unavailable(), unavailable(), unavailable()}}
+	c, stop := dial(t, srv, WithMax(2), WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	_, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("UnaryEcho() = %v, want Unavailable", err)
+	}
+	if got := srv.count(); got != 3 {
+		t.Errorf("server got %d calls, want 3", got)
+	}
+}
+
+func TestUnaryNonRetryableCode(t *testing.T) {
+	srv := &flakyServer{errs: []error{status.Error(codes.InvalidArgument, "bad request")}}
+	c, stop := dial(t, srv, WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	_, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("UnaryEcho() = %v, want InvalidArgument", err)
+	}
+	if got := srv.count(); got != 1 {
+		t.Errorf("server got %d calls, want 1", got)
+	}
+}
+
+func TestUnaryPushback(t *testing.T) {
+	srv := &flakyServer{errs: []error{pushback(t, 100*time.Millisecond)}}
+	c, stop := dial(t, srv, WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	start := time.Now()
+	if _, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"}); err != nil {
+		t.Fatalf("UnaryEcho() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("call took %v, want the 100ms pushback of the server to be honored", elapsed)
+	}
+
+	// A pushback beyond the deadline of the call is not waited for
+	srv = &flakyServer{errs: []error{pushback(t, time.Second)}}
+	c, stop = dial(t, srv, WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "hello"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("UnaryEcho() = %v, want ResourceExhausted", err)
+	}
+	if got := srv.count(); got != 1 {
+		t.Errorf("server got %d calls, want 1", got)
+	}
+}
+
+func TestUnaryPerAttemptTimeout(t *testing.T) {
+	// The first attempt hangs past its timeout, the second one succeeds
+	srv := &flakyServer{errs: []error{errHang}}
+	c, stop := dial(t, srv, WithPerAttemptTimeout(50*time.Millisecond), WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	res, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "hello"})
+	if err != nil {
+		t.Fatalf("UnaryEcho() failed: %v", err)
+	}
+	if res.GetMessage() != "hello" {
+		t.Errorf("UnaryEcho() = %q, want %q", res.GetMessage(), "hello")
+	}
+	if got := srv.count(); got != 2 {
+		t.Errorf("server got %d calls, want 2", got)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("call took %v, want the 50ms of the first attempt and little more", elapsed)
+	}
+}
+
+func TestUnaryBudget(t *testing.T) {
+	// Retries are allowed while more than 2 of the 4 tokens are left: the
+	// first failure leaves 3 tokens, the second one 2
+	var errs []error
+	for i := 0; i < 10; i++ {
+		errs = append(errs, unavailable())
+	}
+	srv := &flakyServer{errs: errs}
+	b := NewBudget(4, 1)
+	c, stop := dial(t, srv, WithMax(10), WithBudget(b), WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	if _, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("UnaryEcho() = %v, want Unavailable", err)
+	}
+	if got := srv.count(); got != 2 {
+		t.Errorf("server got %d calls, want 2", got)
+	}
+
+	// Every success gives a token back: two of them allow one retry again
+	srv.mu.Lock()
+	srv.errs = nil
+	srv.mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if _, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"}); err != nil {
+			t.Fatalf("UnaryEcho() failed: %v", err)
+		}
+	}
+	srv.mu.Lock()
+	srv.errs = []error{unavailable(), unavailable()}
+	srv.calls = 0
+	srv.mu.Unlock()
+	if _, err := c.UnaryEcho(context.Background(), &pb.EchoRequest{Message: "hello"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("UnaryEcho() = %v, want Unavailable", err)
+	}
+	if got := srv.count(); got != 2 {
+		t.Errorf("server got %d calls after a success, want 2", got)
+	}
+}
+
+// recvAll reads the responses of stream until it ends.
+func recvAll(stream pb.Echo_ServerStreamingEchoClient) ([]string, error) {
+	var msgs []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, res.GetMessage())
+	}
+}
+
+func TestStreamRetriesBeforeFirstResponse(t *testing.T) {
+	srv := &flakyServer{errs: []error{unavailable(), unavailable()}}
+	c, stop := dial(t, srv, WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	stream, err := c.ServerStreamingEcho(context.Background(), &pb.EchoRequest{Message: "hello"})
+	if err != nil {
+		t.Fatalf("ServerStreamingEcho() failed: %v", err)
+	}
+	msgs, err := recvAll(stream)
+	if err != nil {
+		t.Fatalf("Recv() failed: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0] != "hello" {
+		t.Errorf("received %q, want [hello]", msgs)
+	}
+	if got := srv.count(); got != 3 {
+		t.Errorf("server got %d calls, want 3", got)
+	}
+}
+
+func TestStreamNotRetriedAfterFirstResponse(t *testing.T) {
+	srv := &flakyServer{}
+	c, stop := dial(t, srv, WithBackoff(time.Millisecond, time.Millisecond, 1, 0))
+	defer stop()
+
+	stream, err := c.ServerStreamingEcho(context.Background(), &pb.EchoRequest{Message: "fail after first"})
+	if err != nil {
+		t.Fatalf("ServerStreamingEcho() failed: %v", err)
+	}
+	msgs, err := recvAll(stream)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Recv() = %v, want Unavailable", err)
+	}
+	if len(msgs) != 1 {
+		t.Errorf("received %q, want a single response", msgs)
+	}
+	if got := srv.count(); got != 1 {
+		t.Errorf("server got %d calls, want 1", got)
+	}
+}
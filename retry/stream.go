@@ -0,0 +1,164 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StreamClientInterceptor returns a client-side streaming interceptor that
+// retries the streams failing with one of the retryable codes. A stream is only
+// retried until it receives its first response message: the messages sent so
+// far are buffered and sent again on the new stream. Once a response has been
+// received, the client may have acted on it and errors are returned as is.
+// Messages must not be modified after being sent, since they may be sent again.
+// The per-attempt timeout does not apply to streams.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s := &clientStream{
+			o:        o,
+			ctx:      ctx,
+			desc:     desc,
+			cc:       cc,
+			method:   method,
+			streamer: streamer,
+			callOpts: callOpts,
+		}
+		for attempt := 1; ; attempt++ {
+			err := s.newAttempt()
+			if err == nil {
+				s.attempt = attempt
+				return s, nil
+			}
+			delay, ok := o.delay(ctx, attempt, err, false)
+			if !ok {
+				return nil, err
+			}
+			log.Printf("retry: attempt %d of %s failed with %v, retrying in %v", attempt, method, status.Code(err), delay)
+			if !sleep(ctx, delay) {
+				return nil, err
+			}
+		}
+	}
+}
+
+// clientStream is a grpc.ClientStream that replaces its underlying stream when
+// an attempt fails before the first response message.
+type clientStream struct {
+	o        *options
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	cc       *grpc.ClientConn
+	method   string
+	streamer grpc.Streamer
+	callOpts []grpc.CallOption
+
+	mu        sync.Mutex
+	stream    grpc.ClientStream  // the stream of the current attempt
+	cancel    context.CancelFunc // cancels the current attempt
+	attempt   int
+	sent      []interface{} // messages sent before the first response
+	closeSent bool
+	committed bool // a response has been received, the stream is not retried anymore
+}
+
+// newAttempt starts a new stream and replays the messages sent so far.
+// s.mu must be held, or s not yet shared.
+func (s *clientStream) newAttempt() error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	stream, err := s.streamer(ctx, s.desc, s.cc, s.method, s.callOpts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	for _, m := range s.sent {
+		if err := stream.SendMsg(m); err != nil {
+			// The error of the stream is reported by RecvMsg
+			break
+		}
+	}
+	if s.closeSent {
+		stream.CloseSend()
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.stream, s.cancel = stream, cancel
+	return nil
+}
+
+func (s *clientStream) current() grpc.ClientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream
+}
+
+func (s *clientStream) Header() (metadata.MD, error) { return s.current().Header() }
+
+func (s *clientStream) Trailer() metadata.MD { return s.current().Trailer() }
+
+func (s *clientStream) Context() context.Context { return s.current().Context() }
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.committed {
+		s.sent = append(s.sent, m)
+	}
+	return s.stream.SendMsg(m)
+}
+
+func (s *clientStream) CloseSend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeSent = true
+	return s.stream.CloseSend()
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.current().RecvMsg(m)
+	for {
+		s.mu.Lock()
+		if err == nil && !s.committed {
+			s.committed = true
+			s.sent = nil
+			s.o.budget.onSuccess()
+		}
+		if err == nil || err == io.EOF || s.committed {
+			if err != nil {
+				// The stream is over, release the context of the attempt
+				s.cancel()
+			}
+			s.mu.Unlock()
+			return err
+		}
+		attempt := s.attempt
+		s.mu.Unlock()
+
+		delay, ok := s.o.delay(s.ctx, attempt, err, false)
+		if ok {
+			log.Printf("retry: attempt %d of %s failed with %v, retrying in %v", attempt, s.method, status.Code(err), delay)
+			ok = sleep(s.ctx, delay)
+		}
+		if !ok {
+			// Only RecvMsg replaces s.cancel once the stream is shared
+			s.cancel()
+			return err
+		}
+
+		s.mu.Lock()
+		s.attempt++
+		err = s.newAttempt()
+		stream := s.stream
+		s.mu.Unlock()
+		if err == nil {
+			err = stream.RecvMsg(m)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package shutdown stops the servers gracefully when the process receives
+// SIGINT or SIGTERM:
+//
+//  1. the health service reports NOT_SERVING, and the server deregisters from
+//     the service discovery if any, so that the load balancers and the Watch
+//     clients stop sending new calls;
+//  2. the server stops accepting connections and sends a GOAWAY on the open
+//     ones, so that the clients open their new streams elsewhere while the
+//     calls in flight go on;
+//  3. after the grace period, the calls still in flight, usually long-lived
+//     streams, are cancelled and the connections closed.
+package shutdown
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+)
+
+// DefaultGrace is the default grace period of the calls in flight.
+const DefaultGrace = 10 * time.Second
+
+// notify returns a channel receiving SIGINT and SIGTERM.
+func notify() <-chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	return c
+}
+
+// GRPC stops s gracefully on SIGINT or SIGTERM, h being its health server, and
+// forcibly once grace has elapsed. s.Serve returns once s is stopped. The
+// deregister functions are called first along with h.Shutdown, e.g. to remove
+// s from a registry.
+func GRPC(s *grpc.Server, h *health.Server, grace time.Duration, deregister ...func()) {
+	c := notify()
+	go func() {
+		sig := <-c
+		log.Printf("received %v, shutting down with a grace period of %v", sig, grace)
+		h.Shutdown()
+		for _, fn := range deregister {
+			fn()
+		}
+
+		// GracefulStop sends the GOAWAY, then waits for the calls in flight
+		stopped := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(stopped)
+		}()
+		t := time.NewTimer(grace)
+		defer t.Stop()
+		select {
+		case <-stopped:
+			log.Printf("all calls finished, stopped")
+		case <-t.C:
+			log.Printf("calls still in flight after %v, stopping", grace)
+			s.Stop()
+		}
+	}()
+}
+
+// HTTP stops srv gracefully on SIGINT or SIGTERM, h being the health server of
+// the gRPC server it serves if any, and forcibly once grace has elapsed. The
+// returned channel is closed once srv is stopped: srv.ListenAndServe returns
+// http.ErrServerClosed as soon as the shutdown starts, so main must wait for it.
+//
+// A grpc.Server served through srv with its ServeHTTP method cannot be stopped
+// with GracefulStop, but its HTTP/2 connections get a GOAWAY from srv.Shutdown.
+func HTTP(srv *http.Server, h *health.Server, grace time.Duration) <-chan struct{} {
+	c := notify()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		sig := <-c
+		log.Printf("received %v, shutting down with a grace period of %v", sig, grace)
+		if h != nil {
+			h.Shutdown()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("requests still in flight after %v, stopping", grace)
+			srv.Close()
+			return
+		}
+		log.Printf("all requests finished, stopped")
+	}()
+	return stopped
+}
@@ -0,0 +1,151 @@
+// Package statusdetails decodes and pretty-prints the details attached to a gRPC
+// status with status.WithDetails, so that clients can act on machine-readable
+// failure reasons instead of parsing error strings.
+package statusdetails
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// Details returns the decoded details of the status carried by err. Details
+// whose type is not linked into the binary are skipped.
+func Details(err error) []proto.Message {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return nil
+	}
+	var details []proto.Message
+	for _, d := range st.Details() {
+		if m, ok := d.(proto.Message); ok {
+			details = append(details, m)
+		}
+	}
+	return details
+}
+
+// Find looks for a detail of the same type as target in the status carried by
+// err. If one is found, it is copied into target and Find returns true.
+func Find(err error, target proto.Message) bool {
+	name := proto.MessageName(target)
+	for _, d := range Details(err) {
+		if proto.MessageName(d) == name {
+			target.Reset()
+			proto.Merge(target, d)
+			return true
+		}
+	}
+	return false
+}
+
+// RetryDelay returns the delay the server asked the client to wait before
+// retrying, as carried by an errdetails.RetryInfo detail.
+func RetryDelay(err error) (time.Duration, bool) {
+	var ri errdetails.RetryInfo
+	if !Find(err, &ri) || ri.GetRetryDelay() == nil {
+		return 0, false
+	}
+	d, convErr := ptypes.Duration(ri.GetRetryDelay())
+	if convErr != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// Reason returns the reason and domain of the ErrorInfo detail carried by err, if any.
+func Reason(err error) (reason, domain string, ok bool) {
+	var ei ErrorInfo
+	if !Find(err, &ei) {
+		return "", "", false
+	}
+	return ei.GetReason(), ei.GetDomain(), true
+}
+
+// Fprint writes the code, message and every detail of the status carried by err to w.
+func Fprint(w io.Writer, err error) {
+	st := status.Convert(err)
+	fmt.Fprintf(w, "Error Code: %v\n", st.Code())
+	fmt.Fprintf(w, "Error Description: %v\n", st.Message())
+
+	for _, d := range st.Proto().GetDetails() {
+		fmt.Fprintf(w, "Error Detail (%s):\n", strings.TrimPrefix(d.GetTypeUrl(), "type.googleapis.com/"))
+		var da ptypes.DynamicAny
+		if err := ptypes.UnmarshalAny(d, &da); err != nil {
+			fmt.Fprintf(w, " - undecodable: %v\n", err)
+			continue
+		}
+		printDetail(w, da.Message)
+	}
+}
+
+// Sprint returns what Fprint would write for err.
+func Sprint(err error) string {
+	var buf bytes.Buffer
+	Fprint(&buf, err)
+	return buf.String()
+}
+
+func printDetail(w io.Writer, detail proto.Message) {
+	switch d := detail.(type) {
+	case *errdetails.BadRequest:
+		for _, v := range d.GetFieldViolations() {
+			fmt.Fprintf(w, " - field %q: %s\n", v.GetField(), v.GetDescription())
+		}
+	case *ErrorInfo:
+		fmt.Fprintf(w, " - reason: %s\n", d.GetReason())
+		fmt.Fprintf(w, " - domain: %s\n", d.GetDomain())
+		keys := make([]string, 0, len(d.GetMetadata()))
+		for k := range d.GetMetadata() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, " - metadata %s: %s\n", k, d.GetMetadata()[k])
+		}
+	case *errdetails.RetryInfo:
+		delay, err := ptypes.Duration(d.GetRetryDelay())
+		if err != nil {
+			fmt.Fprintf(w, " - retry delay: %v\n", d.GetRetryDelay())
+			break
+		}
+		fmt.Fprintf(w, " - retry delay: %v\n", delay)
+	case *errdetails.QuotaFailure:
+		for _, v := range d.GetViolations() {
+			fmt.Fprintf(w, " - subject %q: %s\n", v.GetSubject(), v.GetDescription())
+		}
+	case *errdetails.DebugInfo:
+		fmt.Fprintf(w, " - detail: %s\n", d.GetDetail())
+		for _, e := range d.GetStackEntries() {
+			fmt.Fprintf(w, "   %s\n", e)
+		}
+	case *errdetails.LocalizedMessage:
+		fmt.Fprintf(w, " - [%s] %s\n", d.GetLocale(), d.GetMessage())
+	case *errdetails.PreconditionFailure:
+		for _, v := range d.GetViolations() {
+			fmt.Fprintf(w, " - %s %q: %s\n", v.GetType(), v.GetSubject(), v.GetDescription())
+		}
+	case *errdetails.RequestInfo:
+		fmt.Fprintf(w, " - request id: %s\n", d.GetRequestId())
+		if d.GetServingData() != "" {
+			fmt.Fprintf(w, " - serving data: %s\n", d.GetServingData())
+		}
+	case *errdetails.ResourceInfo:
+		fmt.Fprintf(w, " - resource %s %q (owner %q): %s\n", d.GetResourceType(), d.GetResourceName(), d.GetOwner(), d.GetDescription())
+	case *errdetails.Help:
+		for _, l := range d.GetLinks() {
+			fmt.Fprintf(w, " - %s: %s\n", l.GetDescription(), l.GetUrl())
+		}
+	default:
+		// Any other registered message is printed in the protobuf text format
+		fmt.Fprintf(w, " - %s\n", proto.CompactTextString(detail))
+	}
+}
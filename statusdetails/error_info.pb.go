@@ -0,0 +1,113 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: statusdetails/error_info.proto
+
+// ErrorInfo mirrors google.rpc.ErrorInfo, which the version of
+// google.golang.org/genproto we depend on predates. It is declared in a package
+// of its own, so that it does not conflict with the one of genproto once we
+// upgrade, and should then be replaced by it.
+
+package statusdetails
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Describes the cause of the error with structured details.
+type ErrorInfo struct {
+	// The reason of the error. This is a constant value that identifies the
+	// proximate cause of the error, e.g. "RATE_LIMIT_EXCEEDED".
+	Reason string `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	// The logical grouping to which the "reason" belongs, typically the
+	// name of the service that generates the error.
+	Domain string `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Additional structured details about this error.
+	Metadata             map[string]string `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ErrorInfo) Reset()         { *m = ErrorInfo{} }
+func (m *ErrorInfo) String() string { return proto.CompactTextString(m) }
+func (*ErrorInfo) ProtoMessage()    {}
+func (*ErrorInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_764b72840c7b0593, []int{0}
+}
+
+func (m *ErrorInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErrorInfo.Unmarshal(m, b)
+}
+func (m *ErrorInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErrorInfo.Marshal(b, m, deterministic)
+}
+func (m *ErrorInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErrorInfo.Merge(m, src)
+}
+func (m *ErrorInfo) XXX_Size() int {
+	return xxx_messageInfo_ErrorInfo.Size(m)
+}
+func (m *ErrorInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErrorInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErrorInfo proto.InternalMessageInfo
+
+func (m *ErrorInfo) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *ErrorInfo) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *ErrorInfo) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*ErrorInfo)(nil), "statusdetails.ErrorInfo")
+	proto.RegisterMapType((map[string]string)(nil), "statusdetails.ErrorInfo.MetadataEntry")
+}
+
+func init() { proto.RegisterFile("statusdetails/error_info.proto", fileDescriptor_764b72840c7b0593) }
+
+var fileDescriptor_764b72840c7b0593 = []byte{
+	// 226 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0x2b, 0x2e, 0x49, 0x2c,
+	0x29, 0x2d, 0x4e, 0x49, 0x2d, 0x49, 0xcc, 0xcc, 0x29, 0xd6, 0x4f, 0x2d, 0x2a, 0xca, 0x2f, 0x8a,
+	0xcf, 0xcc, 0x4b, 0xcb, 0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x45, 0x91, 0x57, 0xda,
+	0xc3, 0xc8, 0xc5, 0xe9, 0x0a, 0x52, 0xe3, 0x99, 0x97, 0x96, 0x2f, 0x24, 0xc6, 0xc5, 0x56, 0x94,
+	0x9a, 0x58, 0x9c, 0x9f, 0x27, 0xc1, 0xa8, 0xc0, 0xa8, 0xc1, 0x19, 0x04, 0xe5, 0x81, 0xc4, 0x53,
+	0xf2, 0x73, 0x13, 0x33, 0xf3, 0x24, 0x98, 0x20, 0xe2, 0x10, 0x9e, 0x90, 0x13, 0x17, 0x47, 0x6e,
+	0x6a, 0x49, 0x62, 0x4a, 0x62, 0x49, 0xa2, 0x04, 0xb3, 0x02, 0xb3, 0x06, 0xb7, 0x91, 0x9a, 0x1e,
+	0x8a, 0xf9, 0x7a, 0x70, 0xb3, 0xf5, 0x7c, 0xa1, 0x0a, 0x5d, 0xf3, 0x4a, 0x8a, 0x2a, 0x83, 0xe0,
+	0xfa, 0xa4, 0xac, 0xb9, 0x78, 0x51, 0xa4, 0x84, 0x04, 0xb8, 0x98, 0xb3, 0x53, 0x2b, 0xa1, 0x2e,
+	0x00, 0x31, 0x85, 0x44, 0xb8, 0x58, 0xcb, 0x12, 0x73, 0x4a, 0x53, 0xa1, 0xb6, 0x43, 0x38, 0x56,
+	0x4c, 0x16, 0x8c, 0x4e, 0xce, 0x51, 0x8e, 0xe9, 0x99, 0x25, 0x19, 0xa5, 0x49, 0x7a, 0xc9, 0xf9,
+	0xb9, 0xfa, 0xe5, 0x89, 0x79, 0xe9, 0x95, 0x16, 0x96, 0x66, 0x86, 0xfa, 0xe9, 0x45, 0x05, 0xc9,
+	0xba, 0xe9, 0xf9, 0xba, 0x25, 0xa5, 0x25, 0xf9, 0x45, 0x99, 0x89, 0x39, 0xfa, 0x28, 0xae, 0xb2,
+	0x46, 0xe1, 0x25, 0xb1, 0x81, 0x43, 0xc6, 0x18, 0x30, 0x00, 0xe2, 0x70, 0x24, 0x7d, 0x3b, 0x01,
+	0x00, 0x00,
+}
@@ -0,0 +1,4 @@
+#!/bin/bash
+
+# error_info.proto is registered as "statusdetails/error_info.proto", so it is compiled from the repository root
+protoc -I.. --go_out=paths=source_relative:.. statusdetails/error_info.proto
@@ -0,0 +1,219 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a server-side unary interceptor that creates a
+// server span per call, as a child of the traceparent of the incoming metadata.
+func (t *Tracer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := t.startServer(ctx, info.FullMethod)
+		defer span.End()
+
+		messageEvent(span, "RECEIVED", 1, req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			messageEvent(span, "SENT", 1, resp)
+		}
+		finish(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server-side streaming interceptor that
+// creates a server span per stream, with an event per message.
+func (t *Tracer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := t.startServer(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, span: span})
+		finish(span, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a client-side unary interceptor that creates a
+// client span per call, and sends its traceparent in the outgoing metadata.
+func (t *Tracer) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := t.startClient(ctx, method, cc)
+		defer span.End()
+
+		messageEvent(span, "SENT", 1, req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			messageEvent(span, "RECEIVED", 1, reply)
+		}
+		finish(span, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client-side streaming interceptor that
+// creates a client span per stream, with an event per message. The span ends
+// when RecvMsg returns an error (io.EOF meaning success), or the only response
+// of the streams without server streaming: the spans of the streams that the
+// caller does not read to the end are never exported.
+func (t *Tracer) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := t.startClient(ctx, method, cc)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			finish(span, err)
+			span.End()
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, desc: desc, span: span}, nil
+	}
+}
+
+// startServer starts the server span of method, continuing the trace of the caller.
+func (t *Tracer) startServer(ctx context.Context, method string) (context.Context, *Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(TraceparentKey); len(v) > 0 {
+			// An invalid traceparent starts a new trace
+			if sc, err := ParseTraceparent(v[0]); err == nil {
+				ctx = ContextWithRemote(ctx, sc)
+			}
+		}
+	}
+	ctx, span := t.Start(ctx, strings.TrimPrefix(method, "/"), Server)
+	setRPCAttributes(span, method)
+	if p, ok := peer.FromContext(ctx); ok {
+		span.SetAttribute("net.peer.addr", p.Addr.String())
+	}
+	return ctx, span
+}
+
+// startClient starts the client span of method, and returns ctx with its
+// traceparent in the outgoing metadata, replacing any previous one.
+func (t *Tracer) startClient(ctx context.Context, method string, cc *grpc.ClientConn) (context.Context, *Span) {
+	ctx, span := t.Start(ctx, strings.TrimPrefix(method, "/"), Client)
+	setRPCAttributes(span, method)
+	span.SetAttribute("net.peer.name", cc.Target())
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(TraceparentKey, span.SpanContext().Traceparent())
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// setRPCAttributes sets the attributes of the full method, e.g. "/echo.Echo/UnaryEcho".
+func setRPCAttributes(span *Span, method string) {
+	span.SetAttribute("rpc.system", "grpc")
+	service, name := "", strings.TrimPrefix(method, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		service, name = name[:i], name[i+1:]
+	}
+	span.SetAttribute("rpc.service", service)
+	span.SetAttribute("rpc.method", name)
+}
+
+// finish records the status of the call.
+func finish(span *Span, err error) {
+	span.SetAttribute("rpc.grpc.status_code", status.Code(err).String())
+	span.SetError(err)
+}
+
+// messageEvent adds the event of the id-th message sent or received (typ is "SENT" or "RECEIVED").
+func messageEvent(span *Span, typ string, id int, m interface{}) {
+	attrs := []string{"message.type", typ, "message.id", strconv.Itoa(id)}
+	if pm, ok := m.(proto.Message); ok {
+		attrs = append(attrs, "message.uncompressed_size", strconv.Itoa(proto.Size(pm)))
+	}
+	span.AddEvent("message", attrs...)
+}
+
+// counter numbers the messages of a stream in each direction.
+type counter struct {
+	mu       sync.Mutex
+	sent     int
+	received int
+}
+
+func (c *counter) next(sent bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sent {
+		c.sent++
+		return c.sent
+	}
+	c.received++
+	return c.received
+}
+
+// serverStream wraps grpc.ServerStream to carry the span in its context and
+// add an event per message.
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	span *Span
+	n    counter
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		messageEvent(s.span, "SENT", s.n.next(true), m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		messageEvent(s.span, "RECEIVED", s.n.next(false), m)
+	}
+	return err
+}
+
+// clientStream wraps grpc.ClientStream to add an event per message and end the
+// span when the stream is over.
+type clientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	span *Span
+	n    counter
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		messageEvent(s.span, "SENT", s.n.next(true), m)
+	}
+	// An error of SendMsg is also returned by RecvMsg, with the status of the stream
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		finish(s.span, nil)
+		s.span.End()
+	case err != nil:
+		finish(s.span, err)
+		s.span.End()
+	default:
+		messageEvent(s.span, "RECEIVED", s.n.next(false), m)
+		if !s.desc.ServerStreams {
+			finish(s.span, nil)
+			s.span.End()
+		}
+	}
+	return err
+}
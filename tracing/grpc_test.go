@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/wangy8961/grpc-go-tutorial/features/echopb"
+	"github.com/wangy8961/grpc-go-tutorial/features/echoserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts an Echo server traced by server over an in-memory listener, and
+// returns a client traced by client, or not traced if nil, and a function
+// stopping both.
+func dial(t *testing.T, server, client *Tracer) (pb.EchoClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(server.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(server.StreamServerInterceptor()),
+	)
+	pb.RegisterEchoServer(s, &echoserver.Server{})
+	go s.Serve(lis)
+
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return lis.Dial() }),
+	}
+	if client != nil {
+		opts = append(opts,
+			grpc.WithUnaryInterceptor(client.UnaryClientInterceptor()),
+			grpc.WithStreamInterceptor(client.StreamClientInterceptor()),
+		)
+	}
+	conn, err := grpc.Dial("bufconn", opts...)
+	if err != nil {
+		s.Stop()
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return pb.NewEchoClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+// only returns the only span exported by e.
+func only(t *testing.T, e *InMemoryExporter, side string) SpanData {
+	spans := e.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("%d %s spans exported, want 1", len(spans), side)
+	}
+	return spans[0]
+}
+
+func TestInterceptorsContinueTheTrace(t *testing.T) {
+	var serverSpans, clientSpans InMemoryExporter
+	clientTracer := NewTracer(&clientSpans)
+	c, stop := dial(t, NewTracer(&serverSpans), clientTracer)
+	defer stop()
+
+	for _, method := range []string{"echo.Echo/UnaryEcho", "echo.Echo/ServerStreamingEcho"} {
+		serverSpans.Reset()
+		clientSpans.Reset()
+
+		// The calls are made within a span of the client
+		ctx, root := clientTracer.Start(context.Background(), "root", Internal)
+		if method == "echo.Echo/UnaryEcho" {
+			if _, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "hello"}); err != nil {
+				t.Fatalf("UnaryEcho() failed: %v", err)
+			}
+		} else {
+			stream, err := c.ServerStreamingEcho(ctx, &pb.EchoRequest{Message: "hello"})
+			if err != nil {
+				t.Fatalf("ServerStreamingEcho() failed: %v", err)
+			}
+			for {
+				if _, err := stream.Recv(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("Recv() failed: %v", err)
+				}
+			}
+		}
+		root.End()
+
+		server := only(t, &serverSpans, "server")
+		spans := clientSpans.Spans()
+		if len(spans) != 2 {
+			t.Fatalf("%s: %d client spans exported, want 2", method, len(spans))
+		}
+		client := spans[0]
+
+		rootID := root.SpanContext().SpanID.String()
+		if client.Name != method || client.Kind != Client || client.ParentSpanID != rootID {
+			t.Errorf("%s: client span %s (%s) has parent %q, want a client span of %s with parent %s",
+				method, client.Name, client.Kind, client.ParentSpanID, method, rootID)
+		}
+		if server.Name != method || server.Kind != Server || server.ParentSpanID != client.SpanID {
+			t.Errorf("%s: server span %s (%s) has parent %q, want a server span of %s with parent %s",
+				method, server.Name, server.Kind, server.ParentSpanID, method, client.SpanID)
+		}
+		if traceID := root.SpanContext().TraceID.String(); client.TraceID != traceID || server.TraceID != traceID {
+			t.Errorf("%s: trace IDs client %s, server %s, want %s", method, client.TraceID, server.TraceID, traceID)
+		}
+		if client.Status.Code != "OK" || server.Status.Code != "OK" {
+			t.Errorf("%s: status client %q, server %q, want OK", method, client.Status.Code, server.Status.Code)
+		}
+	}
+}
+
+func TestInvalidTraceparentStartsANewTrace(t *testing.T) {
+	var serverSpans InMemoryExporter
+	c, stop := dial(t, NewTracer(&serverSpans), nil)
+	defer stop()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TraceparentKey, "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := c.UnaryEcho(ctx, &pb.EchoRequest{Message: "hello"}); err != nil {
+		t.Fatalf("UnaryEcho() failed: %v", err)
+	}
+	server := only(t, &serverSpans, "server")
+	if server.ParentSpanID != "" || server.TraceID == "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span of trace %s has parent %q, want the root of a new trace", server.TraceID, server.ParentSpanID)
+	}
+}
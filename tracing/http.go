@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"net/http"
+	"net/textproto"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/wangy8961/grpc-go-tutorial/internal/httputil"
+)
+
+// HTTPHandler returns a handler that creates a server span per request handled
+// by next, as a child of the Traceparent header of the request. route returns
+// the route of the request used in the span name, such as the route pattern
+// "/v1/users/{username}".
+//
+// The Traceparent header of the request passed to next is replaced with the one
+// of the new span, so that a grpc-gateway ServeMux created with
+// runtime.WithIncomingHeaderMatcher(IncomingHeaderMatcher) forwards it to the
+// gRPC server in the metadata of the call. The span is also in the context of
+// the request, so the client interceptors of the gateway connection create
+// their spans as its children.
+func (t *Tracer) HTTPHandler(route func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, err := ParseTraceparent(r.Header.Get(TraceparentKey)); err == nil {
+			ctx = ContextWithRemote(ctx, sc)
+		}
+		ctx, span := t.Start(ctx, "HTTP "+r.Method+" "+route(r), Server)
+		defer span.End()
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.route", route(r))
+		span.SetAttribute("http.target", r.URL.RequestURI())
+		span.SetAttribute("net.peer.addr", r.RemoteAddr)
+
+		r = r.WithContext(ctx)
+		// WithContext makes a shallow copy, the header of the caller must not be modified
+		header := make(http.Header, len(r.Header))
+		for k, v := range r.Header {
+			header[k] = v
+		}
+		header.Set(TraceparentKey, span.SpanContext().Traceparent())
+		r.Header = header
+
+		rec := httputil.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		span.SetAttribute("http.status_code", strconv.Itoa(rec.Code))
+		if rec.Code >= 500 {
+			span.SetError(errorStatus(rec.Code))
+		}
+	})
+}
+
+// errorStatus is the error of a span with a server error status code.
+type errorStatus int
+
+func (e errorStatus) Error() string {
+	return strconv.Itoa(int(e)) + " " + http.StatusText(int(e))
+}
+
+// IncomingHeaderMatcher is a runtime.HeaderMatcherFunc for the grpc-gateway
+// ServeMux that forwards the Traceparent header into the gRPC metadata, along
+// with the headers forwarded by runtime.DefaultHeaderMatcher.
+func IncomingHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == textproto.CanonicalMIMEHeaderKey(TraceparentKey) {
+		return TraceparentKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
@@ -0,0 +1,269 @@
+// Package tracing provides a minimal distributed tracing in the style of
+// OpenTelemetry: spans are created per HTTP request, per RPC on both sides,
+// with an event per stream message, and the trace context is propagated
+// between services with the W3C traceparent header, both in HTTP headers and
+// in gRPC metadata. Finished spans are passed to an Exporter, such as the
+// in-memory one for checks without a collector, or the JSON one that writes
+// them to a file.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentKey is the HTTP header and gRPC metadata key of the trace context.
+const TraceparentKey = "traceparent"
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether sc identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats sc as a W3C traceparent header value, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", s)
+	}
+	var sc SpanContext
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", s)
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", s)
+	}
+	return sc, nil
+}
+
+// SpanKind tells the role of a span.
+type SpanKind string
+
+// Span kinds.
+const (
+	Internal SpanKind = "internal"
+	Server   SpanKind = "server"
+	Client   SpanKind = "client"
+)
+
+// Event is something that happened during a span, e.g. a stream message.
+type Event struct {
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Status is the outcome of a span.
+type Status struct {
+	Code    string `json:"code"` // "OK" or "ERROR"
+	Message string `json:"message,omitempty"`
+}
+
+// SpanData is a finished span, as exported.
+type SpanData struct {
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Name         string            `json:"name"`
+	Kind         SpanKind          `json:"kind"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	DurationMS   float64           `json:"duration_ms"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Events       []Event           `json:"events,omitempty"`
+	Status       Status            `json:"status"`
+}
+
+// Span is an operation of a trace. Its methods are safe for concurrent use,
+// and do nothing on a nil Span.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span context of s.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute sets an attribute of s.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+// AddEvent adds an event to s, with attributes as key/value pairs.
+func (s *Span) AddEvent(name string, attrs ...string) {
+	if s == nil {
+		return
+	}
+	e := Event{Name: name, Time: time.Now()}
+	if len(attrs) > 1 {
+		e.Attributes = make(map[string]string, len(attrs)/2)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			e.Attributes[attrs[i]] = attrs[i+1]
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Events = append(s.data.Events, e)
+}
+
+// SetError marks s as failed with the message of err, or as successful if err is nil.
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.data.Status = Status{Code: "ERROR", Message: err.Error()}
+	} else {
+		s.data.Status = Status{Code: "OK"}
+	}
+}
+
+// End ends s and exports it if it is sampled. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	s.data.DurationMS = float64(s.data.End.Sub(s.data.Start)) / float64(time.Millisecond)
+	if s.data.Status.Code == "" {
+		s.data.Status.Code = "OK"
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	if s.sc.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+// Tracer creates spans and passes them to its exporter when they end.
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer returns a Tracer that exports the spans to exporter. A nil exporter
+// drops the spans, but the trace context is still propagated.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the current span of ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns ctx with s as its current span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// remoteKey is the context key of the span context received from another service.
+type remoteKey struct{}
+
+// ContextWithRemote returns ctx with a span context received from another
+// service, which becomes the parent of the next span started with ctx.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Start starts a span that is a child of the current span of ctx, or of the
+// remote span context of ctx, or the root of a new trace, and returns ctx with
+// the new span as its current span. The span must be ended with End.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	var parent SpanContext
+	if s := SpanFromContext(ctx); s != nil {
+		parent = s.sc
+	} else if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = sc
+	}
+
+	s := &Span{tracer: t}
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.sc.Sampled = parent.Sampled
+		s.parent = parent.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:])
+		s.sc.Sampled = true
+	}
+	rand.Read(s.sc.SpanID[:])
+
+	s.data = SpanData{
+		TraceID: s.sc.TraceID.String(),
+		SpanID:  s.sc.SpanID.String(),
+		Name:    name,
+		Kind:    kind,
+		Start:   time.Now(),
+	}
+	if parent.IsValid() {
+		s.data.ParentSpanID = s.parent.String()
+	}
+	return ContextWithSpan(ctx, s), s
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceparent() failed: %v", err)
+	}
+	if got, want := sc.TraceID.String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("TraceID = %s, want %s", got, want)
+	}
+	if got, want := sc.SpanID.String(), "00f067aa0ba902b7"; got != want {
+		t.Errorf("SpanID = %s, want %s", got, want)
+	}
+	if !sc.Sampled {
+		t.Errorf("Sampled = false, want true")
+	}
+
+	// The versions after 00 may add fields, which are ignored
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future"); err != nil {
+		t.Errorf("ParseTraceparent() of a future version failed: %v", err)
+	}
+
+	for _, s := range []string{
+		"",
+		"garbage",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",   // version ff is forbidden
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x", // version 00 has 4 fields
+		"0-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",    // version of 1 digit
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",     // short trace-id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01",     // short parent-id
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",   // not hexadecimal
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",    // flags of 1 digit
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",   // zero trace-id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",   // zero parent-id
+	} {
+		if sc, err := ParseTraceparent(s); err == nil {
+			t.Errorf("ParseTraceparent(%q) = %+v, want an error", s, sc)
+		}
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	_, span := NewTracer(nil).Start(context.Background(), "root", Internal)
+	for _, sampled := range []bool{true, false} {
+		sc := span.SpanContext()
+		sc.Sampled = sampled
+		got, err := ParseTraceparent(sc.Traceparent())
+		if err != nil {
+			t.Fatalf("ParseTraceparent(%q) failed: %v", sc.Traceparent(), err)
+		}
+		if got != sc {
+			t.Errorf("ParseTraceparent(%q) = %+v, want %+v", sc.Traceparent(), got, sc)
+		}
+	}
+}
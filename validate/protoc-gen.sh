@@ -0,0 +1,4 @@
+#!/bin/bash
+
+# validate.proto is imported as "validate/validate.proto", so it is compiled from the repository root
+protoc -I.. --go_out=paths=source_relative:.. validate/validate.proto
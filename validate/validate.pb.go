@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: validate/validate.proto
+
+package validate
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// FieldRules define the constraints of a single field.
+// Unset rules are not checked.
+type FieldRules struct {
+	// required rejects the zero value of the field: an empty string, a zero
+	// number or enum, an unset message or an empty repeated field.
+	Required *bool `protobuf:"varint,1,opt,name=required" json:"required,omitempty"`
+	// min_len is the minimum length of a string, counted in characters.
+	MinLen *uint64 `protobuf:"varint,2,opt,name=min_len,json=minLen" json:"min_len,omitempty"`
+	// max_len is the maximum length of a string, counted in characters.
+	MaxLen *uint64 `protobuf:"varint,3,opt,name=max_len,json=maxLen" json:"max_len,omitempty"`
+	// pattern is a regular expression (RE2 syntax) that a string must match.
+	Pattern *string `protobuf:"bytes,4,opt,name=pattern" json:"pattern,omitempty"`
+	// gt, gte, lt and lte bound the value of a number.
+	Gt  *float64 `protobuf:"fixed64,5,opt,name=gt" json:"gt,omitempty"`
+	Gte *float64 `protobuf:"fixed64,6,opt,name=gte" json:"gte,omitempty"`
+	Lt  *float64 `protobuf:"fixed64,7,opt,name=lt" json:"lt,omitempty"`
+	Lte *float64 `protobuf:"fixed64,8,opt,name=lte" json:"lte,omitempty"`
+	// defined_only rejects enum values that are not declared in the enum.
+	DefinedOnly *bool `protobuf:"varint,9,opt,name=defined_only,json=definedOnly" json:"defined_only,omitempty"`
+	// in lists the allowed values of a string, or the allowed names of an enum.
+	In                   []string `protobuf:"bytes,10,rep,name=in" json:"in,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FieldRules) Reset()         { *m = FieldRules{} }
+func (m *FieldRules) String() string { return proto.CompactTextString(m) }
+func (*FieldRules) ProtoMessage()    {}
+func (*FieldRules) Descriptor() ([]byte, []int) {
+	return fileDescriptor_79dbefd0936fb92e, []int{0}
+}
+
+func (m *FieldRules) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FieldRules.Unmarshal(m, b)
+}
+func (m *FieldRules) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FieldRules.Marshal(b, m, deterministic)
+}
+func (m *FieldRules) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FieldRules.Merge(m, src)
+}
+func (m *FieldRules) XXX_Size() int {
+	return xxx_messageInfo_FieldRules.Size(m)
+}
+func (m *FieldRules) XXX_DiscardUnknown() {
+	xxx_messageInfo_FieldRules.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FieldRules proto.InternalMessageInfo
+
+func (m *FieldRules) GetRequired() bool {
+	if m != nil && m.Required != nil {
+		return *m.Required
+	}
+	return false
+}
+
+func (m *FieldRules) GetMinLen() uint64 {
+	if m != nil && m.MinLen != nil {
+		return *m.MinLen
+	}
+	return 0
+}
+
+func (m *FieldRules) GetMaxLen() uint64 {
+	if m != nil && m.MaxLen != nil {
+		return *m.MaxLen
+	}
+	return 0
+}
+
+func (m *FieldRules) GetPattern() string {
+	if m != nil && m.Pattern != nil {
+		return *m.Pattern
+	}
+	return ""
+}
+
+func (m *FieldRules) GetGt() float64 {
+	if m != nil && m.Gt != nil {
+		return *m.Gt
+	}
+	return 0
+}
+
+func (m *FieldRules) GetGte() float64 {
+	if m != nil && m.Gte != nil {
+		return *m.Gte
+	}
+	return 0
+}
+
+func (m *FieldRules) GetLt() float64 {
+	if m != nil && m.Lt != nil {
+		return *m.Lt
+	}
+	return 0
+}
+
+func (m *FieldRules) GetLte() float64 {
+	if m != nil && m.Lte != nil {
+		return *m.Lte
+	}
+	return 0
+}
+
+func (m *FieldRules) GetDefinedOnly() bool {
+	if m != nil && m.DefinedOnly != nil {
+		return *m.DefinedOnly
+	}
+	return false
+}
+
+func (m *FieldRules) GetIn() []string {
+	if m != nil {
+		return m.In
+	}
+	return nil
+}
+
+var E_Rules = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*FieldRules)(nil),
+	Field:         51001,
+	Name:          "validate.rules",
+	Tag:           "bytes,51001,opt,name=rules",
+	Filename:      "validate/validate.proto",
+}
+
+func init() {
+	proto.RegisterType((*FieldRules)(nil), "validate.FieldRules")
+	proto.RegisterExtension(E_Rules)
+}
+
+func init() { proto.RegisterFile("validate/validate.proto", fileDescriptor_79dbefd0936fb92e) }
+
+var fileDescriptor_79dbefd0936fb92e = []byte{
+	// 316 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x44, 0x91, 0xcd, 0x4a, 0x03, 0x31,
+	0x10, 0xc7, 0xc9, 0xf6, 0x6b, 0x9b, 0x8a, 0xc8, 0x22, 0x34, 0x14, 0x84, 0xd5, 0xd3, 0x5e, 0xba,
+	0x8b, 0x1e, 0xd4, 0xea, 0xcd, 0x83, 0x17, 0x85, 0x42, 0x8e, 0x5e, 0x4a, 0xda, 0x4c, 0x63, 0x20,
+	0x4d, 0xd6, 0x74, 0x56, 0xdb, 0x97, 0xf0, 0x7d, 0x7c, 0x26, 0x5f, 0x42, 0x36, 0xdb, 0xad, 0xb7,
+	0xf9, 0x7f, 0x30, 0x64, 0x7e, 0xa1, 0xe3, 0x4f, 0x61, 0xb4, 0x14, 0x08, 0x45, 0x3b, 0xe4, 0xa5,
+	0x77, 0xe8, 0x92, 0xb8, 0xd5, 0x93, 0x54, 0x39, 0xa7, 0x0c, 0x14, 0xc1, 0x5f, 0x56, 0xeb, 0x42,
+	0xc2, 0x76, 0xe5, 0x75, 0x89, 0xce, 0x37, 0xdd, 0xab, 0x5f, 0x42, 0xe9, 0xb3, 0x06, 0x23, 0x79,
+	0x65, 0x60, 0x9b, 0x4c, 0x68, 0xec, 0xe1, 0xa3, 0xd2, 0x1e, 0x24, 0x23, 0x29, 0xc9, 0x62, 0x7e,
+	0xd4, 0xc9, 0x98, 0x0e, 0x36, 0xda, 0x2e, 0x0c, 0x58, 0x16, 0xa5, 0x24, 0xeb, 0xf2, 0xfe, 0x46,
+	0xdb, 0x57, 0xb0, 0x21, 0x10, 0xbb, 0x10, 0x74, 0x0e, 0x81, 0xd8, 0xd5, 0x01, 0xa3, 0x83, 0x52,
+	0x20, 0x82, 0xb7, 0xac, 0x9b, 0x92, 0x6c, 0xc8, 0x5b, 0x99, 0x9c, 0xd2, 0x48, 0x21, 0xeb, 0xa5,
+	0x24, 0x23, 0x3c, 0x52, 0x98, 0x9c, 0xd1, 0x8e, 0x42, 0x60, 0xfd, 0x60, 0xd4, 0x63, 0xdd, 0x30,
+	0xc8, 0x06, 0x4d, 0xc3, 0x84, 0x86, 0x41, 0x60, 0x71, 0xd3, 0x30, 0x08, 0xc9, 0x25, 0x3d, 0x91,
+	0xb0, 0xd6, 0x16, 0xe4, 0xc2, 0x59, 0xb3, 0x67, 0xc3, 0xf0, 0xde, 0xd1, 0xc1, 0x9b, 0x5b, 0xb3,
+	0xaf, 0x97, 0x68, 0xcb, 0x68, 0xda, 0xc9, 0x86, 0x3c, 0xd2, 0xf6, 0xe1, 0x85, 0xf6, 0x7c, 0xb8,
+	0xf3, 0x22, 0x6f, 0xc8, 0xe4, 0x2d, 0x99, 0x3c, 0x40, 0x98, 0x97, 0xa8, 0x9d, 0xdd, 0xb2, 0x9f,
+	0xef, 0xfa, 0x90, 0xd1, 0xcd, 0x79, 0x7e, 0x44, 0xfb, 0x0f, 0x89, 0x37, 0x3b, 0x9e, 0x66, 0x6f,
+	0x77, 0x4a, 0xe3, 0x7b, 0xb5, 0xcc, 0x57, 0x6e, 0x53, 0x7c, 0x09, 0xab, 0xf6, 0xf7, 0xb3, 0xdb,
+	0xeb, 0x42, 0xf9, 0x72, 0x35, 0x55, 0x6e, 0x8a, 0x15, 0x3a, 0xaf, 0x85, 0x39, 0x7e, 0xcf, 0x63,
+	0x3b, 0xfc, 0x0d, 0x00, 0x51, 0x9a, 0x74, 0x95, 0xbb, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,276 @@
+// Package validate checks protobuf messages against the (validate.rules) field
+// options declared in their .proto files, and reports every violation as an
+// errdetails.BadRequest attached to a codes.InvalidArgument status.
+package validate
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/descriptor"
+	"github.com/golang/protobuf/proto"
+	pbdescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// field is a message field together with its compiled rules.
+type field struct {
+	name    string // proto field name, used in violation paths
+	index   int    // index of the field in the Go struct
+	desc    *pbdescriptor.FieldDescriptorProto
+	rules   *FieldRules
+	pattern *regexp.Regexp
+}
+
+// cache holds the fields of every message type seen so far, keyed by reflect.Type.
+var cache sync.Map
+
+// fieldsOf returns the fields of msg that carry rules or may contain nested rules.
+func fieldsOf(msg descriptor.Message) ([]field, error) {
+	t := reflect.TypeOf(msg)
+	if f, ok := cache.Load(t); ok {
+		return f.([]field), nil
+	}
+
+	_, md := descriptor.ForMessage(msg)
+	props := proto.GetProperties(t.Elem())
+
+	var fields []field
+	for _, fd := range md.GetField() {
+		f := field{name: fd.GetName(), index: -1, desc: fd}
+		for i, p := range props.Prop {
+			if p.OrigName == fd.GetName() {
+				f.index = i
+				break
+			}
+		}
+		if f.index < 0 {
+			// oneof members live in a wrapper field and are not validated
+			continue
+		}
+
+		if fd.GetOptions() != nil && proto.HasExtension(fd.GetOptions(), E_Rules) {
+			ext, err := proto.GetExtension(fd.GetOptions(), E_Rules)
+			if err != nil {
+				return nil, fmt.Errorf("validate: failed to read rules of %s.%s: %v", md.GetName(), fd.GetName(), err)
+			}
+			f.rules = ext.(*FieldRules)
+			if f.rules.Pattern != nil {
+				re, err := regexp.Compile(f.rules.GetPattern())
+				if err != nil {
+					return nil, fmt.Errorf("validate: invalid pattern of %s.%s: %v", md.GetName(), fd.GetName(), err)
+				}
+				f.pattern = re
+			}
+		}
+
+		if f.rules != nil || fd.GetType() == pbdescriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			fields = append(fields, f)
+		}
+	}
+
+	cache.Store(t, fields)
+	return fields, nil
+}
+
+// Violations returns every rule violated by msg, including the violations of
+// its nested messages. Messages without generated descriptors are not checked.
+func Violations(msg proto.Message) ([]*errdetails.BadRequest_FieldViolation, error) {
+	var v []*errdetails.BadRequest_FieldViolation
+	if err := collect(msg, "", &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func collect(msg proto.Message, prefix string, v *[]*errdetails.BadRequest_FieldViolation) error {
+	dm, ok := msg.(descriptor.Message)
+	if !ok || reflect.ValueOf(msg).IsNil() {
+		return nil
+	}
+	fields, err := fieldsOf(dm)
+	if err != nil {
+		return err
+	}
+
+	sv := reflect.ValueOf(msg).Elem()
+	for _, f := range fields {
+		path := prefix + f.name
+		fv := sv.Field(f.index)
+
+		for _, desc := range f.check(fv) {
+			*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: path, Description: desc})
+		}
+
+		if f.desc.GetType() != pbdescriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			continue
+		}
+		if fv.Kind() == reflect.Slice {
+			for i := 0; i < fv.Len(); i++ {
+				if nested, ok := fv.Index(i).Interface().(proto.Message); ok {
+					if err := collect(nested, fmt.Sprintf("%s[%d].", path, i), v); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if nested, ok := fv.Interface().(proto.Message); ok {
+			if err := collect(nested, path+".", v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check returns a description of every rule of f violated by the value fv.
+func (f *field) check(fv reflect.Value) []string {
+	r := f.rules
+	if r == nil {
+		return nil
+	}
+
+	if r.GetRequired() && isZero(fv) {
+		return []string{"value is required"}
+	}
+
+	var descs []string
+	switch fv.Kind() {
+	case reflect.String:
+		s := fv.String()
+		n := uint64(utf8.RuneCountInString(s))
+		if r.MinLen != nil && n < r.GetMinLen() {
+			descs = append(descs, fmt.Sprintf("length must be at least %d characters", r.GetMinLen()))
+		}
+		if r.MaxLen != nil && n > r.GetMaxLen() {
+			descs = append(descs, fmt.Sprintf("length must be at most %d characters", r.GetMaxLen()))
+		}
+		if f.pattern != nil && !f.pattern.MatchString(s) {
+			descs = append(descs, fmt.Sprintf("value must match pattern %q", r.GetPattern()))
+		}
+		if len(r.In) > 0 && !contains(r.In, s) {
+			descs = append(descs, fmt.Sprintf("value must be one of [%s]", strings.Join(r.In, ", ")))
+		}
+
+	case reflect.Int32, reflect.Int64:
+		if f.desc.GetType() == pbdescriptor.FieldDescriptorProto_TYPE_ENUM {
+			descs = append(descs, f.checkEnum(int32(fv.Int()))...)
+			break
+		}
+		descs = append(descs, checkRange(r, float64(fv.Int()))...)
+
+	case reflect.Uint32, reflect.Uint64:
+		descs = append(descs, checkRange(r, float64(fv.Uint()))...)
+
+	case reflect.Float32, reflect.Float64:
+		descs = append(descs, checkRange(r, fv.Float())...)
+	}
+	return descs
+}
+
+// checkEnum checks the defined_only and in rules of an enum field.
+func (f *field) checkEnum(n int32) []string {
+	r := f.rules
+	// enum type names are fully qualified with a leading dot, e.g. ".user.Role"
+	values := proto.EnumValueMap(strings.TrimPrefix(f.desc.GetTypeName(), "."))
+
+	name, defined := "", false
+	for k, v := range values {
+		if v == n {
+			name, defined = k, true
+			break
+		}
+	}
+
+	var descs []string
+	if r.GetDefinedOnly() && !defined {
+		descs = append(descs, fmt.Sprintf("value %d is not a defined enum value", n))
+	}
+	if len(r.In) > 0 && !contains(r.In, name) {
+		descs = append(descs, fmt.Sprintf("value must be one of [%s]", strings.Join(r.In, ", ")))
+	}
+	return descs
+}
+
+// checkRange checks the gt, gte, lt and lte rules of a number field.
+func checkRange(r *FieldRules, n float64) []string {
+	var descs []string
+	if r.Gt != nil && !(n > r.GetGt()) {
+		descs = append(descs, fmt.Sprintf("value must be greater than %s", formatFloat(r.GetGt())))
+	}
+	if r.Gte != nil && !(n >= r.GetGte()) {
+		descs = append(descs, fmt.Sprintf("value must be greater than or equal to %s", formatFloat(r.GetGte())))
+	}
+	if r.Lt != nil && !(n < r.GetLt()) {
+		descs = append(descs, fmt.Sprintf("value must be less than %s", formatFloat(r.GetLt())))
+	}
+	if r.Lte != nil && !(n <= r.GetLte()) {
+		descs = append(descs, fmt.Sprintf("value must be less than or equal to %s", formatFloat(r.GetLte())))
+	}
+	return descs
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns nil if msg satisfies all of its rules. Otherwise it returns a
+// codes.InvalidArgument status error with an errdetails.BadRequest that lists
+// every field violation.
+func Validate(msg proto.Message) error {
+	v, err := Violations(msg)
+	if err != nil {
+		// e.g. a malformed rule: a bug of the server, not of the caller
+		log.Printf("validate: failed to validate %s: %v", proto.MessageName(msg), err)
+		return status.Error(codes.Internal, "internal error")
+	}
+	if len(v) == 0 {
+		return nil
+	}
+
+	descs := make([]string, 0, len(v))
+	for _, fv := range v {
+		descs = append(descs, fv.GetField()+": "+fv.GetDescription())
+	}
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s", proto.MessageName(msg), strings.Join(descs, "; ")))
+	if ds, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = ds
+	}
+	return st.Err()
+}